@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+// bufferedResponseWriter captures a complete response so it can be inspected or
+// rewritten before being sent to the client.
+type bufferedResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+	raw    bool // The body was written directly rather than by the Protocol.
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: http.Header{}}
+}
+
+func (b *bufferedResponseWriter) Header() http.Header { return b.header }
+
+func (b *bufferedResponseWriter) WriteHeader(code int) {
+	if b.code == 0 {
+		b.code = code
+	}
+}
+
+func (b *bufferedResponseWriter) Write(data []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(data)
+}
+
+// reset discards the buffered response.
+func (b *bufferedResponseWriter) reset() {
+	b.header = http.Header{}
+	b.code = 0
+	b.body.Reset()
+	b.raw = false
+}
+
+// flush the buffered response to w with an explicit Content-Length.
+func (b *bufferedResponseWriter) flush(w http.ResponseWriter) error {
+	if bw, ok := w.(*bufferedResponseWriter); ok {
+		bw.raw = bw.raw || b.raw
+	}
+	for key, values := range b.header {
+		w.Header()[key] = values
+	}
+	code := b.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	if code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified {
+		w.Header().Set("Content-Length", strconv.Itoa(b.body.Len()))
+	}
+	w.WriteHeader(code)
+	_, err := w.Write(b.body.Bytes())
+	return err
+}
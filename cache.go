@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"container/list"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithHandlerResultCache caches the successful results of a route's handler, keyed by the
+// values of its path parameters, eg. "/users/:id" is cached per id.
+//
+// Entries expire after ttl, and at most maxSize entries are kept, evicting the least
+// recently used. Cached results are shared between requests so must not be mutated.
+// Entries may be removed early with Router.InvalidateCache.
+//
+// Only GET and HEAD routes may be cached, as the key doesn't include the request body
+// and other methods are expected to have side effects.
+//
+// Bodies that can only be written once or are evaluated per request, such as Raw,
+// io.Reader, http.Handler and lazily evaluated bodies, can't be cached. Routes statically
+// returning them may not use the cache, and such results are otherwise not cached.
+func WithHandlerResultCache(ttl time.Duration, maxSize int) RouteOption {
+	return func(r *route) {
+		r.cache = &resultCache{ttl: ttl, maxSize: maxSize, entries: map[string]*list.Element{}, lru: list.New()}
+	}
+}
+
+// InvalidateCache removes the cached results of the routes registered with path for the
+// given path parameter values.
+//
+// eg.
+//
+//	r.InvalidateCache("/users/:id", map[string]string{"id": "42"})
+func (r *Router) InvalidateCache(path string, params map[string]string) {
+	for _, rt := range r.routes {
+		if rt.path == path && rt.cache != nil {
+			rt.cache.invalidate(rt.cacheKey(func(name string) string {
+				if transform := rt.paramTransforms[name]; transform != nil {
+					return transform(params[name])
+				}
+				return params[name]
+			}))
+		}
+	}
+}
+
+var (
+	handlerType = reflect.TypeOf((*http.Handler)(nil)).Elem()
+	thunkType   = reflect.TypeOf(func() (interface{}, error) { return nil, nil })
+)
+
+// isUncacheableType returns true if bodies of type t can't be cached.
+func isUncacheableType(t reflect.Type) bool {
+	return isRawType(t) || t.Implements(handlerType) || t == thunkType
+}
+
+// isCacheable returns true if the results of a handler may be cached.
+func isCacheable(results []reflect.Value) bool {
+	if !results[len(results)-1].IsNil() {
+		return false
+	}
+	if len(results) == 1 || results[0].Type() == reflect.TypeOf(StatusCode(0)) {
+		return true
+	}
+	body := results[0].Interface()
+	if response := asResponse(body); response != nil {
+		body = response.Body
+	}
+	if body == nil {
+		return true
+	}
+	return !isUncacheableType(reflect.TypeOf(body))
+}
+
+// cacheKey builds the key for a set of path parameter values.
+func (r route) cacheKey(value func(name string) string) string {
+	values := []string{}
+	for _, name := range pathParamNames(r.path) {
+		values = append(values, value(name))
+	}
+	return strings.Join(values, "\x00")
+}
+
+type cacheEntry struct {
+	key     string
+	expires time.Time
+	results []reflect.Value
+}
+
+// resultCache is an LRU cache of handler results with a TTL.
+type resultCache struct {
+	ttl     time.Duration
+	maxSize int
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+func (c *resultCache) get(key string) []reflect.Value {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry.results
+}
+
+func (c *resultCache) put(key string, results []reflect.Value) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, expires: time.Now().Add(c.ttl), results: results})
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *resultCache) invalidate(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
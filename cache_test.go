@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerResultCache(t *testing.T) {
+	calls := map[string]int{}
+	r := New()
+	r.Get("/users/:id", func(id string) (string, error) {
+		calls[id]++
+		return fmt.Sprintf("%s-%d", id, calls[id]), nil
+	}, WithHandlerResultCache(time.Minute, 10))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(path string) string {
+		actual := ""
+		resp := getAndDecode(t, server, path, &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return actual
+	}
+
+	require.Equal(t, "1-1", get("/users/1"))
+	require.Equal(t, "1-1", get("/users/1"))
+	require.Equal(t, "2-1", get("/users/2"))
+	require.Equal(t, "2-1", get("/users/2"))
+
+	r.InvalidateCache("/users/:id", map[string]string{"id": "1"})
+	require.Equal(t, "1-2", get("/users/1"))
+	require.Equal(t, "2-1", get("/users/2"))
+}
+
+func TestHandlerResultCacheEviction(t *testing.T) {
+	calls := 0
+	r := New()
+	r.Get("/users/:id", func(id string) (string, error) {
+		calls++
+		return id, nil
+	}, WithHandlerResultCache(time.Minute, 1))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	getAndDecode(t, server, "/users/1", &actual)
+	getAndDecode(t, server, "/users/2", &actual)
+	getAndDecode(t, server, "/users/1", &actual)
+	require.Equal(t, 3, calls)
+}
+
+func TestHandlerResultCacheUncacheable(t *testing.T) {
+	r := New()
+	require.Panics(t, func() {
+		r.Get("/files/:id", func(id string) (io.Reader, error) { return strings.NewReader(id), nil },
+			WithHandlerResultCache(time.Minute, 10))
+	})
+
+	calls := 0
+	r.Get("/reports/:id", func(id string) (interface{}, error) {
+		calls++
+		return &Raw{ContentType: "text/plain", Body: strings.NewReader(id)}, nil
+	}, WithHandlerResultCache(time.Minute, 10))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, body := getRaw(t, server, "/reports/42")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "42", body)
+	}
+	require.Equal(t, 2, calls)
+}
+
+func TestHandlerResultCacheReadOnly(t *testing.T) {
+	r := New()
+	handler := func(id string, body string) (string, error) { return body, nil }
+	for method, register := range map[string]func(string, interface{}, ...RouteOption) *Router{
+		"POST": r.Post, "PUT": r.Put, "PATCH": r.Patch,
+	} {
+		require.Panics(t, func() { register("/items/:id", handler, WithHandlerResultCache(time.Minute, 10)) }, method)
+	}
+	require.Panics(t, func() {
+		r.Delete("/items/:id", func(id string) error { return nil }, WithHandlerResultCache(time.Minute, 10))
+	})
+	require.NotPanics(t, func() {
+		r.Get("/items/:id", func(id string) (string, error) { return id, nil }, WithHandlerResultCache(time.Minute, 10))
+	})
+}
@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Transcoder converts a UTF-8 response body to another character set.
+type Transcoder func(body []byte) ([]byte, error)
+
+// WithTranscoder transcodes responses to charset for clients that prefer it in their
+// Accept-Charset header.
+//
+// Responses are otherwise sent as UTF-8. The charset parameter of the response
+// Content-Type is updated to match. Only text, JSON and XML responses are transcoded,
+// while Raw bodies are always sent unchanged.
+func WithTranscoder(charset string, transcoder Transcoder) Option {
+	return func(r *Router) {
+		if r.transcoders == nil {
+			r.transcoders = map[string]Transcoder{}
+		}
+		r.transcoders[strings.ToLower(charset)] = transcoder
+	}
+}
+
+// negotiateCharset returns the charset the response to req should be transcoded to, or
+// "" if it should be left as UTF-8.
+func (r *Router) negotiateCharset(req *http.Request) string {
+	header := req.Header.Get("Accept-Charset")
+	if len(r.transcoders) == 0 || header == "" {
+		return ""
+	}
+	type candidate struct {
+		charset string
+		q       float64
+	}
+	candidates := []candidate{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		charset := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		if _, ok := r.transcoders[charset]; (ok || charset == "utf-8") && q > 0 {
+			candidates = append(candidates, candidate{charset, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) == 0 || candidates[0].charset == "utf-8" {
+		return ""
+	}
+	return candidates[0].charset
+}
+
+// transcode the buffered response body to charset.
+//
+// Only textual bodies encoded by the Protocol are transcoded, raw and binary bodies are
+// left unchanged.
+func (r *Router) transcode(bw *bufferedResponseWriter, charset string) error {
+	if bw.raw || !isTextual(bw.header.Get("Content-Type")) {
+		return nil
+	}
+	body, err := r.transcoders[charset](bw.body.Bytes())
+	if err != nil {
+		return err
+	}
+	bw.body.Reset()
+	bw.body.Write(body) // nolint
+	if mediaType, params, err := mime.ParseMediaType(bw.header.Get("Content-Type")); err == nil {
+		params["charset"] = charset
+		bw.header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	}
+	return nil
+}
+
+// isTextual returns true if contentType is a text, JSON or XML media type.
+func isTextual(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
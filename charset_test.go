@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func latin1(body []byte) ([]byte, error) {
+	out := []byte{}
+	for _, r := range string(body) {
+		if r > 0xff {
+			return nil, fmt.Errorf("can't represent %q in ISO-8859-1", r)
+		}
+		out = append(out, byte(r))
+	}
+	return out, nil
+}
+
+func TestTranscoder(t *testing.T) {
+	r := New(WithTranscoder("ISO-8859-1", latin1))
+	r.Get("/word", func() (string, error) { return "café", nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(t *testing.T, acceptCharset string) (*http.Response, string) {
+		req, err := http.NewRequest("GET", server.URL+"/word", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Charset", acceptCharset)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return resp, string(body[:n])
+	}
+
+	t.Run("Transcoded", func(t *testing.T) {
+		resp, body := get(t, "iso-8859-1, utf-8;q=0.5")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json; charset=iso-8859-1", resp.Header.Get("Content-Type"))
+		require.Equal(t, "\"caf\xe9\"\n", body)
+	})
+
+	t.Run("PreferUTF8", func(t *testing.T) {
+		resp, body := get(t, "utf-8, iso-8859-1;q=0.5")
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.Equal(t, "\"café\"\n", body)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		resp, body := get(t, "koi8-r")
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.Equal(t, "\"café\"\n", body)
+	})
+}
+
+func TestTranscoderSkipsRawBodies(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff\xfe")
+	r := New(WithTranscoder("ISO-8859-1", latin1))
+	r.Get("/image", func() (*Raw, error) { return &Raw{Body: bytes.NewReader(png)}, nil })
+	r.Get("/notes", func() (*Raw, error) {
+		return &Raw{ContentType: "text/plain", Body: strings.NewReader("café")}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(path string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Charset", "iso-8859-1")
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	resp, body := get("/image")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, png, body)
+
+	resp, body = get("/notes")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	require.Equal(t, "café", string(body))
+}
@@ -3,23 +3,296 @@ package rest
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strings"
 )
 
 // DefaultProtocol implements a default JSON protocol with a standard error format.
+//
+// Request body fields that are not decoded into a struct may be captured by tagging a
+// field of type map[string]json.RawMessage with `unknown:",extra"`.
+//
+// eg.
+//
+//	type User struct {
+//		Name   string                     `json:"name"`
+//		Extras map[string]json.RawMessage `json:"-" unknown:",extra"`
+//	}
 var DefaultProtocol Protocol = defaultProtocol{}
 
-type defaultProtocol struct{}
+// A ProtocolOption configures a protocol created with NewDefaultProtocol.
+type ProtocolOption func(d *defaultProtocol)
+
+// WithFieldNameMapper transforms the object keys of decoded request bodies before they are
+// matched against struct fields.
+//
+// This allows clients using a different naming convention to be supported without
+// per-field tags. See SnakeToCamelCase.
+func WithFieldNameMapper(mapper func(string) string) ProtocolOption {
+	return func(d *defaultProtocol) {
+		d.fieldNameMapper = mapper
+	}
+}
+
+// WithMaxBodyFields limits the total number of object keys in a request body, guarding
+// against bodies crafted to be expensive to decode.
+//
+// Bodies exceeding the limit are rejected with a 400 before being decoded.
+func WithMaxBodyFields(n int) ProtocolOption {
+	return func(d *defaultProtocol) {
+		d.maxFields = n
+	}
+}
+
+// An ErrorEncoder returns the body to encode for an error response with the given status.
+//
+// This allows a protocol to use different error shapes for different classes of error, eg.
+// detailed bodies for client errors and generic bodies for server errors.
+type ErrorEncoder func(status int, err error) interface{}
+
+// WithErrorEncoder overrides the body encoded for error responses.
+//
+// Errors wrapping a TypedError are unaffected, as they already carry their own body.
+func WithErrorEncoder(encoder ErrorEncoder) ProtocolOption {
+	return func(d *defaultProtocol) {
+		d.errorEncoder = encoder
+	}
+}
+
+// WithErrorContentType sets the Content-Type of error responses, eg. "application/problem+json".
+//
+// Successful responses are unaffected.
+func WithErrorContentType(contentType string) ProtocolOption {
+	return func(d *defaultProtocol) {
+		d.errorContentType = contentType
+	}
+}
+
+// SnakeToCamelCase converts a snake_case name to camelCase.
+func SnakeToCamelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// NewDefaultProtocol creates a new instance of the default JSON protocol with the given options.
+func NewDefaultProtocol(options ...ProtocolOption) Protocol {
+	d := defaultProtocol{}
+	for _, option := range options {
+		option(&d)
+	}
+	return d
+}
+
+type defaultProtocol struct {
+	fieldNameMapper  func(string) string
+	maxFields        int
+	errorEncoder     ErrorEncoder
+	errorContentType string
+}
 
 func (d defaultProtocol) DecodeClientRequest(req *http.Request, v interface{}) error {
-	return json.NewDecoder(req.Body).Decode(v)
+	var body io.Reader = req.Body
+	if d.maxFields > 0 {
+		buf := &bytes.Buffer{}
+		if err := checkFieldCount(io.TeeReader(req.Body, buf), d.maxFields); err != nil {
+			return err
+		}
+		body = buf
+	}
+	if extras := extrasField(v); extras != nil {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if err := d.decode(bytes.NewReader(data), v); err != nil {
+			return err
+		}
+		return d.captureExtras(data, v, extras)
+	}
+	return d.decode(body, v)
+}
+
+func (d defaultProtocol) decode(body io.Reader, v interface{}) error {
+	if d.fieldNameMapper == nil {
+		return json.NewDecoder(body).Decode(v)
+	}
+	// Decode into a generic value first so object keys can be rewritten.
+	var raw interface{}
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(mapFieldNames(raw, d.fieldNameMapper))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// extrasField returns the field of the struct pointed to by v that is tagged with
+// `unknown:",extra"`, or nil if there is no such field.
+//
+// The field must be of type map[string]json.RawMessage.
+func extrasField(v interface{}) *reflect.StructField {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	t = t.Elem()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("unknown") == ",extra" {
+			if field.Type != rawMessageMapType {
+				panic("field " + field.Name + " of " + t.String() + " tagged with unknown:\",extra\" must be of type map[string]json.RawMessage")
+			}
+			return &field
+		}
+	}
+	return nil
+}
+
+var rawMessageMapType = reflect.TypeOf(map[string]json.RawMessage{})
+
+// captureExtras decodes the JSON object in data a second time, storing any keys that do
+// not correspond to fields of v in the extras field.
+func (d defaultProtocol) captureExtras(data []byte, v interface{}, extras *reflect.StructField) error {
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	known := jsonFieldNames(reflect.TypeOf(v).Elem())
+	captured := map[string]json.RawMessage{}
+	for key, value := range object {
+		name := key
+		if d.fieldNameMapper != nil {
+			name = d.fieldNameMapper(key)
+		}
+		if !known[strings.ToLower(name)] {
+			captured[key] = value
+		}
+	}
+	if len(captured) > 0 {
+		reflect.ValueOf(v).Elem().FieldByIndex(extras.Index).Set(reflect.ValueOf(captured))
+	}
+	return nil
+}
+
+// jsonFieldNames returns the lower-cased names that encoding/json decodes into fields of t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		switch {
+		case field.Tag.Get("unknown") != "" || name == "-" && tag == "-":
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			for embedded := range jsonFieldNames(field.Type) {
+				names[embedded] = true
+			}
+		case field.PkgPath != "":
+		case name != "":
+			names[strings.ToLower(name)] = true
+		default:
+			names[strings.ToLower(field.Name)] = true
+		}
+	}
+	return names
+}
+
+// checkFieldCount scans the JSON value in r and returns an error if it has more than
+// max object keys in total.
+func checkFieldCount(r io.Reader, max int) error {
+	type container struct {
+		object    bool
+		expectKey bool
+	}
+	dec := json.NewDecoder(r)
+	stack := []*container{}
+	count := 0
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var top *container
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				if top != nil && top.object {
+					top.expectKey = true
+				}
+				stack = append(stack, &container{object: delim == '{', expectKey: true})
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		} else if top != nil && top.object {
+			if top.expectKey {
+				count++
+				if count > max {
+					return Errorf(http.StatusBadRequest, "request body exceeds the maximum of %d fields", max)
+				}
+			}
+			top.expectKey = !top.expectKey
+		}
+		if len(stack) == 0 {
+			return nil
+		}
+	}
+}
+
+func mapFieldNames(v interface{}, mapper func(string) string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			out[mapper(key)] = mapFieldNames(value, mapper)
+		}
+		return out
+	case []interface{}:
+		for i, value := range v {
+			v[i] = mapFieldNames(value, mapper)
+		}
+		return v
+	}
+	return v
 }
 
 func (d defaultProtocol) EncodeServerResponse(req *http.Request, w http.ResponseWriter, code int, err error, v interface{}) error {
 	if err != nil {
-		response, ok := err.(*ErrorResponse)
-		if ok {
+		contentType := "application/json"
+		if d.errorContentType != "" {
+			contentType = d.errorContentType
+		}
+		var typed *TypedError
+		if errors.As(err, &typed) {
+			return writeJSON(w, typed.Status, contentType, typed.Body)
+		}
+		var unauthorized *UnauthorizedError
+		if errors.As(err, &unauthorized) && unauthorized.Challenge != "" {
+			w.Header().Set("WWW-Authenticate", unauthorized.Challenge)
+		}
+		var tooManyRequests *TooManyRequestsError
+		if errors.As(err, &tooManyRequests) {
+			tooManyRequests.setHeaders(w.Header())
+		}
+		var response *ErrorResponse
+		if errors.As(err, &response) {
 			code = response.Status
 		} else {
 			if code == 0 {
@@ -27,7 +300,10 @@ func (d defaultProtocol) EncodeServerResponse(req *http.Request, w http.Response
 			}
 			response = &ErrorResponse{Status: code, Message: err.Error()}
 		}
-		return d.EncodeServerResponse(req, w, code, nil, response)
+		if d.errorEncoder != nil {
+			return writeJSON(w, code, contentType, d.errorEncoder(code, err))
+		}
+		return writeJSON(w, code, contentType, response)
 	}
 
 	if code == 0 {
@@ -39,7 +315,15 @@ func (d defaultProtocol) EncodeServerResponse(req *http.Request, w http.Response
 			code = http.StatusOK
 		}
 	}
-	w.Header().Add("Content-Type", "application/json")
+	return writeJSON(w, code, "application/json", v)
+}
+
+func writeJSON(w http.ResponseWriter, code int, contentType string, v interface{}) error {
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return nil
+	}
+	w.Header().Add("Content-Type", contentType)
 	w.WriteHeader(code)
 	return json.NewEncoder(w).Encode(v)
 }
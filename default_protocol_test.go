@@ -0,0 +1,230 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldNameMapper(t *testing.T) {
+	type address struct {
+		StreetName string
+	}
+	type person struct {
+		FirstName string
+		LastName  string
+		Addresses []address
+	}
+	r := New(WithProtocol(NewDefaultProtocol(WithFieldNameMapper(SnakeToCamelCase))))
+	r.Post("/people", func(p *person) (*person, error) {
+		return p, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &person{}
+	resp := postAndDecode(t, server, "/people", map[string]interface{}{
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"addresses":  []interface{}{map[string]interface{}{"street_name": "Main"}},
+	}, actual)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, &person{FirstName: "Jane", LastName: "Doe", Addresses: []address{{StreetName: "Main"}}}, actual)
+}
+
+func TestSnakeToCamelCase(t *testing.T) {
+	require.Equal(t, "firstName", SnakeToCamelCase("first_name"))
+	require.Equal(t, "aBC", SnakeToCamelCase("a_b_c"))
+	require.Equal(t, "name", SnakeToCamelCase("name"))
+}
+
+func TestMaxBodyFields(t *testing.T) {
+	r := New(WithProtocol(NewDefaultProtocol(WithMaxBodyFields(4))))
+	r.Post("/", func(body map[string]interface{}) (int, error) {
+		return len(body), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("WithinLimit", func(t *testing.T) {
+		actual := 0
+		resp := postAndDecode(t, server, "/", map[string]interface{}{
+			"a": 1,
+			"b": []interface{}{map[string]interface{}{"c": 1}},
+			"d": map[string]interface{}{},
+		}, &actual)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, 3, actual)
+	})
+
+	t.Run("ExceedsLimit", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := postAndDecode(t, server, "/", map[string]interface{}{
+			"a": 1,
+			"b": []interface{}{map[string]interface{}{"c": 1, "d": 2}},
+			"e": "5",
+		}, actual)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, Error(http.StatusBadRequest, "request body exceeds the maximum of 4 fields"), actual)
+	})
+}
+
+func TestTypedError(t *testing.T) {
+	type fieldError struct {
+		Field  string `json:"field"`
+		Reason string `json:"reason"`
+	}
+	type validationErrors struct {
+		Errors []fieldError `json:"errors"`
+	}
+	r := New()
+	r.Post("/users", func(body map[string]string) error {
+		return fmt.Errorf("invalid user: %w", NewTypedError(http.StatusBadRequest, &validationErrors{
+			Errors: []fieldError{{Field: "email", Reason: "required"}},
+		}))
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &validationErrors{}
+	resp := postAndDecode(t, server, "/users", map[string]string{}, actual)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, &validationErrors{Errors: []fieldError{{Field: "email", Reason: "required"}}}, actual)
+}
+
+func TestErrorEncoder(t *testing.T) {
+	type clientError struct {
+		Code   int    `json:"code"`
+		Detail string `json:"detail"`
+	}
+	type serverError struct {
+		Message string `json:"message"`
+	}
+	r := New(WithProtocol(NewDefaultProtocol(WithErrorEncoder(func(status int, err error) interface{} {
+		if status >= 500 {
+			return map[string]string{"message": "internal error"}
+		}
+		return &clientError{Code: status, Detail: err.Error()}
+	}))))
+	r.Get("/client", func() error { return Errorf(http.StatusBadRequest, "missing name") })
+	r.Get("/server", func() error { return fmt.Errorf("database unavailable") })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	client := &clientError{}
+	resp := getAndDecode(t, server, "/client", client)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, &clientError{Code: http.StatusBadRequest, Detail: "400: missing name"}, client)
+
+	serverErr := &serverError{}
+	resp = getAndDecode(t, server, "/server", serverErr)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, &serverError{Message: "internal error"}, serverErr)
+}
+
+func TestUnauthorizedError(t *testing.T) {
+	r := New()
+	r.Get("/me", func() (string, error) {
+		return "", fmt.Errorf("authenticating: %w", Unauthorized(`Bearer realm="api"`, "token expired"))
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &ErrorResponse{}
+	resp := getAndDecode(t, server, "/me", actual)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, `Bearer realm="api"`, resp.Header.Get("WWW-Authenticate"))
+	require.Equal(t, Error(http.StatusUnauthorized, "token expired"), actual)
+}
+
+func TestUnknownFieldExtras(t *testing.T) {
+	type base struct {
+		ID int `json:"id"`
+	}
+	type user struct {
+		base
+		Name   string `json:"name"`
+		Age    int
+		Extras map[string]json.RawMessage `json:"-" unknown:",extra"`
+	}
+	var actual *user
+	r := New()
+	r.Post("/users", func(u *user) error {
+		actual = u
+		return nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp := postAndDecode(t, server, "/users", map[string]interface{}{
+		"id":    1,
+		"name":  "Bob",
+		"age":   42,
+		"x-foo": "bar",
+		"x-baz": []int{1, 2},
+	}, nil)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, &user{
+		base: base{ID: 1},
+		Name: "Bob",
+		Age:  42,
+		Extras: map[string]json.RawMessage{
+			"x-foo": json.RawMessage(`"bar"`),
+			"x-baz": json.RawMessage(`[1,2]`),
+		},
+	}, actual)
+}
+
+func TestErrorContentType(t *testing.T) {
+	r := New(WithProtocol(NewDefaultProtocol(WithErrorContentType("application/problem+json"))))
+	r.Get("/users/:id", func(id int) (string, error) {
+		if id == 0 {
+			return "", Errorf(http.StatusNotFound, "user %d not found", id)
+		}
+		return "Bob", nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/users/1", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	errResp := &ErrorResponse{}
+	resp = getAndDecode(t, server, "/users/0", errResp)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	require.Equal(t, Error(http.StatusNotFound, "user 0 not found"), errResp)
+}
+
+func TestTooManyRequests(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second)
+	r := New()
+	r.Get("/search", func() (string, error) { return "", TooManyRequests(100, 0, reset) })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &ErrorResponse{}
+	resp := getAndDecode(t, server, "/search", actual)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, "100", resp.Header.Get("X-RateLimit-Limit"))
+	require.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	require.Equal(t, strconv.FormatInt(reset.Unix(), 10), resp.Header.Get("X-RateLimit-Reset"))
+	require.Equal(t, "30", resp.Header.Get("Retry-After"))
+	require.Equal(t, Error(http.StatusTooManyRequests, "rate limit of 100 exceeded"), actual)
+}
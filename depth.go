@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithMaxEncodeDepth limits the nesting depth of response bodies, so that cyclic data
+// structures result in an error response rather than unbounded recursion.
+//
+// Bodies nested more deeply than depth are rejected before any of the response is
+// written, with the status set by WithEncodingErrorStatus.
+func WithMaxEncodeDepth(depth int) Option {
+	return func(r *Router) {
+		r.maxEncodeDepth = depth
+	}
+}
+
+// checkDepth returns an error if v is nested more than max levels deep.
+func checkDepth(v reflect.Value, depth, max int) error {
+	if depth > max {
+		return fmt.Errorf("response body exceeds the maximum depth of %d, it may be cyclic", max)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkDepth(v.Elem(), depth+1, max)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := checkDepth(v.Field(i), depth+1, max); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkDepth(iter.Value(), depth+1, max); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if isScalarKind(v.Type().Elem().Kind()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkDepth(v.Index(i), depth+1, max); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isScalarKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
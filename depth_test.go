@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type depthNode struct {
+	Name string
+	Next *depthNode
+}
+
+func TestMaxEncodeDepth(t *testing.T) {
+	r := New(WithMaxEncodeDepth(32), WithEncodingErrorStatus(http.StatusBadGateway))
+	r.Get("/cycle", func() (*depthNode, error) {
+		a := &depthNode{Name: "a"}
+		a.Next = &depthNode{Name: "b", Next: a}
+		return a, nil
+	})
+	r.Get("/list", func() (*depthNode, error) {
+		return &depthNode{Name: "a", Next: &depthNode{Name: "b"}}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &ErrorResponse{}
+	resp := getAndDecode(t, server, "/cycle", actual)
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	require.Equal(t, "failed to encode response: response body exceeds the maximum depth of 32, it may be cyclic", actual.Message)
+
+	list := &depthNode{}
+	resp = getAndDecode(t, server, "/list", list)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, &depthNode{Name: "a", Next: &depthNode{Name: "b"}}, list)
+}
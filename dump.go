@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+// WithRequestDump writes a dump of every request, including its headers and body, to w.
+//
+// This is intended for local debugging only, as requests may contain credentials and
+// other sensitive data. Request bodies remain available to handlers.
+func WithRequestDump(w io.Writer) Option {
+	return func(r *Router) {
+		r.requestDump = &requestDumper{w: w}
+	}
+}
+
+type requestDumper struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// dump req, preserving its body.
+func (d *requestDumper) dump(req *http.Request) error {
+	data, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	_, err = d.w.Write(append(data, '\n'))
+	return err
+}
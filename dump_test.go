@@ -0,0 +1,26 @@
+package rest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestDump(t *testing.T) {
+	dump := &bytes.Buffer{}
+	r := New(WithRequestDump(dump))
+	r.Post("/users", func(user map[string]string) (string, error) { return user["name"], nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := postAndDecode(t, server, "/users", map[string]string{"name": "Bob"}, &actual)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "Bob", actual)
+	require.Contains(t, dump.String(), "POST /users HTTP/1.1\r\n")
+	require.Contains(t, dump.String(), `{"name":"Bob"}`)
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ExampleRequest generates an example request for the route registered with method and path.
+//
+// Path parameters are filled in with placeholder values and, if the handler accepts a body,
+// an example body is encoded with the Router's Protocol. Body fields use the value of their
+// "example" tag if present, or the zero value otherwise.
+func (r *Router) ExampleRequest(method, path string) (*http.Request, error) {
+	for _, rt := range r.routes {
+		if rt.method != method || rt.path != path {
+			continue
+		}
+		pathTypes, bodyType := routeInputs(rt)
+		parts := strings.Split(rt.path, "/")
+		for i, part := range parts {
+			if strings.HasPrefix(part, ":") {
+				parts[i] = exampleParam(part[1:], pathTypes[part[1:]])
+			}
+		}
+		req, err := http.NewRequest(method, strings.Join(parts, "/"), http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		if bodyType != nil {
+			if err := r.protocol.EncodeClientRequest(req, exampleValue(bodyType, 0).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return req, nil
+	}
+	return nil, fmt.Errorf("no route for %s %s", method, path)
+}
+
+// ExampleCurl generates an example curl command for the route registered with method and
+// path, as a request to baseURL. See ExampleRequest for details.
+func (r *Router) ExampleCurl(baseURL, method, path string) (string, error) {
+	req, err := r.ExampleRequest(method, path)
+	if err != nil {
+		return "", err
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	cmd := []string{"curl", "-X", method}
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmd = append(cmd, "-H", shellQuote(key+": "+req.Header.Get(key)))
+	}
+	if len(body) > 0 {
+		cmd = append(cmd, "-d", shellQuote(strings.TrimSpace(string(body))))
+	}
+	cmd = append(cmd, shellQuote(strings.TrimSuffix(baseURL, "/")+req.URL.Path))
+	return strings.Join(cmd, " "), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// routeInputs returns the types of the path parameters and body accepted by the handler
+// for rt, using the same rules as the Router.
+func routeInputs(rt route) (map[string]reflect.Type, reflect.Type) {
+	ft := reflect.TypeOf(rt.handler)
+	params := pathParamNames(rt.path)
+	pathTypes := map[string]reflect.Type{}
+	var body reflect.Type
+	for i := 0; i < ft.NumIn(); i++ {
+		pt := ft.In(i)
+		switch {
+		case injectedParamBuilder(pt) != nil:
+		case isParamsType(pt):
+			st := pt
+			if st.Kind() == reflect.Ptr {
+				st = st.Elem()
+			}
+			for j := 0; j < st.NumField(); j++ {
+				field := st.Field(j)
+				if name, ok := field.Tag.Lookup("path"); ok {
+					pathTypes[name] = field.Type
+				} else if _, ok := field.Tag.Lookup("body"); ok {
+					body = field.Type
+				}
+			}
+		case len(pathTypes) < len(params):
+			pathTypes[params[len(pathTypes)]] = pt
+		default:
+			body = pt
+		}
+	}
+	return pathTypes, body
+}
+
+func exampleParam(name string, t reflect.Type) string {
+	if t == nil {
+		return name
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return name
+	case reflect.Bool:
+		return "true"
+	default:
+		return "1"
+	}
+}
+
+// exampleValue creates an example value of type t.
+func exampleValue(t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if depth > 8 {
+		return v
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if t != bigIntType {
+			v.Set(exampleValue(t.Elem(), depth+1).Addr())
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			v.Set(reflect.Append(v, exampleValue(t.Elem(), depth+1)))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			example, ok := field.Tag.Lookup("example")
+			if ok && isScalarParamType(field.Type) {
+				value, err := (&Router{}).pathParamBuilder(field.Type, field.Name, func(*http.Request) string { return example })(nil)
+				if err == nil {
+					v.Field(i).Set(value.Convert(field.Type))
+					continue
+				}
+			}
+			v.Field(i).Set(exampleValue(field.Type, depth+1))
+		}
+	}
+	return v
+}
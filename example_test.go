@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exampleRole string
+
+type exampleUser struct {
+	Name  string      `json:"name" example:"Bob"`
+	Age   int         `json:"age" example:"42"`
+	Admin bool        `json:"admin"`
+	Tags  []string    `json:"tags"`
+	Role  exampleRole `json:"role" example:"editor"`
+}
+
+func TestExampleRequest(t *testing.T) {
+	r := New()
+	r.Post("/groups/:group/users", func(group string, user *exampleUser) error { return nil })
+	r.Get("/users/:id", func(id int) (*exampleUser, error) { return nil, nil })
+
+	req, err := r.ExampleRequest("POST", "/groups/:group/users")
+	require.NoError(t, err)
+	require.Equal(t, "/groups/group/users", req.URL.Path)
+	require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	data, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	user := &exampleUser{}
+	err = json.Unmarshal(data, user)
+	require.NoError(t, err)
+	require.Equal(t, &exampleUser{Name: "Bob", Age: 42, Tags: []string{""}, Role: "editor"}, user)
+
+	req, err = r.ExampleRequest("GET", "/users/:id")
+	require.NoError(t, err)
+	require.Equal(t, "/users/1", req.URL.Path)
+
+	cmd, err := r.ExampleCurl("http://localhost:8080/", "GET", "/users/:id")
+	require.NoError(t, err)
+	require.Equal(t, "curl -X GET 'http://localhost:8080/users/1'", cmd)
+
+	cmd, err = r.ExampleCurl("http://localhost:8080/", "POST", "/groups/:group/users")
+	require.NoError(t, err)
+	require.Equal(t, "curl -X POST "+
+		"-H 'Accept: application/json' -H 'Content-Type: application/json' "+
+		`-d '{"name":"Bob","age":42,"admin":false,"tags":[""],"role":"editor"}' `+
+		"'http://localhost:8080/groups/group/users'", cmd)
+
+	_, err = r.ExampleRequest("DELETE", "/users/:id")
+	require.Error(t, err)
+}
+
+func TestExampleRequestSend(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(id int) (*exampleUser, error) { return &exampleUser{Name: "Bob"}, nil })
+	r.Get("/files/%zz", func() error { return nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, err := r.ExampleRequest("GET", "/users/:id")
+	require.NoError(t, err)
+	base, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	req.URL = base.ResolveReference(req.URL)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = r.ExampleRequest("GET", "/files/%zz")
+	require.Error(t, err)
+}
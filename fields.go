@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// WithSparseFieldsets allows clients to select the top-level fields included in struct
+// response bodies with the "fields" query parameter, eg. "/users/1?fields=id,name".
+//
+// Fields are named as they are in the JSON encoding of the response, and this applies to
+// structs and slices of structs. Unknown fields are ignored.
+//
+// Projected bodies are passed to the Protocol as generic JSON values, ie. maps, slices and
+// json.Number, so this is only suitable for JSON protocols.
+func WithSparseFieldsets() Option {
+	return func(r *Router) {
+		r.sparseFieldsets = true
+	}
+}
+
+// sparseFieldset projects body onto the fields requested by req.
+func sparseFieldset(req *http.Request, body interface{}) (interface{}, error) {
+	query := req.URL.Query().Get("fields")
+	if query == "" || !isProjectableType(reflect.TypeOf(body)) {
+		return body, nil
+	}
+	fields := map[string]bool{}
+	for _, field := range strings.Split(query, ",") {
+		fields[strings.TrimSpace(field)] = true
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	// Decode numbers as json.Number so that large integers are not rounded to float64.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	project := func(v interface{}) {
+		if object, ok := v.(map[string]interface{}); ok {
+			for key := range object {
+				if !fields[key] {
+					delete(object, key)
+				}
+			}
+		}
+	}
+	if array, ok := generic.([]interface{}); ok {
+		for _, element := range array {
+			project(element)
+		}
+	} else {
+		project(generic)
+	}
+	return generic, nil
+}
+
+// isProjectableType returns true if t is a struct or slice of structs, or pointers to them.
+func isProjectableType(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseFieldsets(t *testing.T) {
+	type user struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	r := New(WithSparseFieldsets())
+	r.Get("/users/:id", func(id int) (*user, error) { return &user{ID: id, Name: "Bob", Email: "bob@example.com"}, nil })
+	r.Get("/users", func() ([]user, error) { return []user{{ID: 1, Name: "Bob"}, {ID: 2, Name: "Alice"}}, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := map[string]interface{}{}
+	resp := getAndDecode(t, server, "/users/1?fields=id,name", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, map[string]interface{}{"id": 1.0, "name": "Bob"}, actual)
+
+	list := []map[string]interface{}{}
+	resp = getAndDecode(t, server, "/users?fields=name", &list)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []map[string]interface{}{{"name": "Bob"}, {"name": "Alice"}}, list)
+
+	actual = map[string]interface{}{}
+	resp = getAndDecode(t, server, "/users/1", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, actual, 3)
+}
+
+func TestSparseFieldsetsLargeIntegers(t *testing.T) {
+	type account struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	r := New(WithSparseFieldsets())
+	r.Get("/accounts/:id", func(id int64) (*account, error) { return &account{ID: id, Name: "Bob"}, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, body := getRaw(t, server, "/accounts/9007199254740993?fields=id")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"id":9007199254740993}`, strings.TrimSpace(body))
+}
@@ -1,5 +1,7 @@
 module github.com/alecthomas/rest
 
+go 1.19
+
 require (
 	github.com/bmizerany/pat v0.0.0-20170815010413-6226ea591a40
 	github.com/davecgh/go-spew v1.1.0 // indirect
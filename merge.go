@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Precedence determines which source wins when a field is supplied by both the query
+// string and the request body.
+type Precedence int
+
+const (
+	// BodyPrecedence prefers values from the request body.
+	BodyPrecedence Precedence = iota + 1
+	// QueryPrecedence prefers values from the query string.
+	QueryPrecedence
+)
+
+// WithMergeQueryAndBody populates struct request bodies from query parameters as well as the
+// body itself, with precedence determining which wins if both supply a field.
+//
+// Query parameters are matched against the JSON names of the struct's scalar fields. The
+// request body may be empty, in which case the struct is populated from the query alone.
+func WithMergeQueryAndBody(precedence Precedence) Option {
+	return func(r *Router) {
+		r.mergeQuery = precedence
+	}
+}
+
+// queryMerger returns a function that decodes a request into a pointer to pt, merging in
+// query parameters, or nil if merging is not enabled or applicable to pt.
+func (r *Router) queryMerger(pt reflect.Type) func(req *http.Request, v interface{}) error {
+	if r.mergeQuery == 0 || pt.Kind() != reflect.Struct {
+		return nil
+	}
+	type queryField struct {
+		index   int
+		name    string
+		builder paramBuilder
+	}
+	fields := []queryField{}
+	for i := 0; i < pt.NumField(); i++ {
+		field := pt.Field(i)
+		if field.PkgPath != "" || !isScalarParamType(field.Type) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, queryField{i, name, r.pathParamBuilder(field.Type, name, queryValue(name))})
+	}
+	applyQuery := func(req *http.Request, v reflect.Value) error {
+		query := req.URL.Query()
+		for _, field := range fields {
+			if _, ok := query[field.name]; !ok {
+				continue
+			}
+			value, err := field.builder(req)
+			if err != nil {
+				return Errorf(http.StatusBadRequest, "invalid query parameter %s: %s", field.name, err)
+			}
+			v.Field(field.index).Set(value.Convert(pt.Field(field.index).Type))
+		}
+		return nil
+	}
+	return func(req *http.Request, v interface{}) error {
+		rv := reflect.ValueOf(v).Elem()
+		if r.mergeQuery == BodyPrecedence {
+			if err := applyQuery(req, rv); err != nil {
+				return err
+			}
+		}
+		if err := r.protocol.DecodeClientRequest(req, v); err != nil && err != io.EOF {
+			return err
+		}
+		if r.mergeQuery == QueryPrecedence {
+			if err := applyQuery(req, rv); err != nil {
+				return err
+			}
+		}
+		return validate(v)
+	}
+}
@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type searchRequest struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+	Sort  string `json:"sort"`
+}
+
+func TestMergeQueryAndBody(t *testing.T) {
+	post := func(t *testing.T, precedence Precedence, query, body string) (*http.Response, *searchRequest) {
+		var actual *searchRequest
+		r := New(WithMergeQueryAndBody(precedence))
+		r.Post("/search", func(req *searchRequest) error {
+			actual = req
+			return nil
+		})
+		server := httptest.NewServer(r)
+		defer server.Close()
+		resp, err := server.Client().Post(server.URL+"/search?"+query, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp, actual
+	}
+
+	t.Run("BodyPrecedence", func(t *testing.T) {
+		resp, actual := post(t, BodyPrecedence, "name=query&limit=5", `{"name": "body", "sort": "asc"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, &searchRequest{Name: "body", Limit: 5, Sort: "asc"}, actual)
+	})
+
+	t.Run("QueryPrecedence", func(t *testing.T) {
+		resp, actual := post(t, QueryPrecedence, "name=query&limit=5", `{"name": "body", "sort": "asc"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, &searchRequest{Name: "query", Limit: 5, Sort: "asc"}, actual)
+	})
+
+	t.Run("EmptyBody", func(t *testing.T) {
+		resp, actual := post(t, BodyPrecedence, "name=query", ``)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, &searchRequest{Name: "query"}, actual)
+	})
+
+	t.Run("InvalidQuery", func(t *testing.T) {
+		resp, _ := post(t, BodyPrecedence, "limit=lots", `{}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+type searchSort string
+
+func TestMergeQueryAndBodyNamedTypes(t *testing.T) {
+	type namedSearchRequest struct {
+		Name  string     `json:"name"`
+		Limit uint16     `json:"limit"`
+		Sort  searchSort `json:"sort"`
+	}
+	var actual *namedSearchRequest
+	r := New(WithMergeQueryAndBody(QueryPrecedence))
+	r.Post("/search", func(req *namedSearchRequest) error {
+		actual = req
+		return nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := server.Client().Post(server.URL+"/search?sort=desc&limit=5", "application/json", strings.NewReader(`{"name": "body", "sort": "asc"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, &namedSearchRequest{Name: "body", Limit: 5, Sort: "desc"}, actual)
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+)
+
+type oneOf struct {
+	discriminator string
+	variants      map[string]oneOfVariant
+}
+
+type oneOfVariant struct {
+	t     reflect.Type
+	isPtr bool
+}
+
+// WithOneOf allows request bodies of an interface type to be decoded into one of several
+// concrete types, selected by the value of a discriminator field in the body.
+//
+// iface must be a pointer to the interface type, and each variant a value (or pointer) of a
+// type implementing it.
+//
+// eg.
+//
+//	rest.WithOneOf((*Shape)(nil), "type", map[string]interface{}{
+//		"circle": &Circle{},
+//		"square": &Square{},
+//	})
+func WithOneOf(iface interface{}, discriminator string, variants map[string]interface{}) Option {
+	it := reflect.TypeOf(iface)
+	if it == nil || it.Kind() != reflect.Ptr || it.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("expected a pointer to an interface but got %T", iface))
+	}
+	it = it.Elem()
+	union := &oneOf{discriminator: discriminator, variants: map[string]oneOfVariant{}}
+	for key, variant := range variants {
+		vt := reflect.TypeOf(variant)
+		if vt == nil || !vt.Implements(it) {
+			panic(fmt.Sprintf("variant %q of type %T does not implement %s", key, variant, it))
+		}
+		if vt.Kind() == reflect.Ptr {
+			union.variants[key] = oneOfVariant{t: vt.Elem(), isPtr: true}
+		} else {
+			union.variants[key] = oneOfVariant{t: vt}
+		}
+	}
+	return func(r *Router) {
+		if r.oneOfs == nil {
+			r.oneOfs = map[reflect.Type]*oneOf{}
+		}
+		r.oneOfs[it] = union
+	}
+}
+
+func (r *Router) oneOfBuilder(union *oneOf) paramBuilder {
+	return func(req *http.Request) (reflect.Value, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		// Decode once to find the discriminator, then again into the selected variant.
+		fields := map[string]interface{}{}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err := r.protocol.DecodeClientRequest(req, &fields); err != nil {
+			return reflect.Value{}, err
+		}
+		key, _ := fields[union.discriminator].(string)
+		variant, ok := union.variants[key]
+		if !ok {
+			return reflect.Value{}, Errorf(http.StatusUnprocessableEntity, "unknown %s %q", union.discriminator, key)
+		}
+		v := reflect.New(variant.t)
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err := r.decodeBody(req, v.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		if !variant.isPtr {
+			v = v.Elem()
+		}
+		return v, nil
+	}
+}
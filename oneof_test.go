@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type shape interface {
+	Area() float64
+}
+
+type circle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c *circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type square struct {
+	Side float64 `json:"side"`
+}
+
+func (s square) Area() float64 { return s.Side * s.Side }
+
+func TestOneOf(t *testing.T) {
+	r := New(WithOneOf((*shape)(nil), "type", map[string]interface{}{
+		"circle": &circle{},
+		"square": square{},
+	}))
+	r.Post("/area", func(s shape) (string, error) {
+		return fmt.Sprintf("%T %.0f", s, s.Area()), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Circle", func(t *testing.T) {
+		actual := ""
+		resp := postAndDecode(t, server, "/area", map[string]interface{}{"type": "circle", "radius": 2}, &actual)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, "*rest.circle 12", actual)
+	})
+
+	t.Run("Square", func(t *testing.T) {
+		actual := ""
+		resp := postAndDecode(t, server, "/area", map[string]interface{}{"type": "square", "side": 3}, &actual)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, "rest.square 9", actual)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := postAndDecode(t, server, "/area", map[string]interface{}{"type": "hexagon"}, actual)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		require.Equal(t, Error(http.StatusUnprocessableEntity, `unknown type "hexagon"`), actual)
+	})
+
+	t.Run("InvalidVariant", func(t *testing.T) {
+		require.Panics(t, func() {
+			WithOneOf((*shape)(nil), "type", map[string]interface{}{"circle": circle{}})
+		})
+	})
+}
@@ -0,0 +1,307 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas,omitempty"`
+}
+
+type openAPIOperation struct {
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema             `json:"schema"`
+	Examples map[string]*openAPIExample `json:"examples,omitempty"`
+}
+
+type openAPIExample struct {
+	Value interface{} `json:"value"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+}
+
+// WithRequestExample adds a named example request body to the OpenAPI spec for a route.
+func WithRequestExample(name string, body interface{}) RouteOption {
+	return func(r *route) {
+		if r.requestExamples == nil {
+			r.requestExamples = map[string]interface{}{}
+		}
+		r.requestExamples[name] = body
+	}
+}
+
+// WithResponseExample adds a named example response body to the OpenAPI spec for a route.
+func WithResponseExample(name string, body interface{}) RouteOption {
+	return func(r *route) {
+		if r.responseExamples == nil {
+			r.responseExamples = map[string]interface{}{}
+		}
+		r.responseExamples[name] = body
+	}
+}
+
+// OpenAPI generates an OpenAPI 3.0 document describing all registered routes.
+//
+// Path parameters, request bodies and response bodies are derived from each handler's
+// signature using the same rules the Router uses to map requests onto handlers. Struct
+// types are emitted under "components/schemas" and referenced with "$ref".
+func (r *Router) OpenAPI() ([]byte, error) {
+	doc := &openAPIDocument{
+		OpenAPI:    "3.0.3",
+		Info:       openAPIInfo{Title: "API", Version: "1.0.0"},
+		Paths:      map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{Schemas: map[string]*openAPISchema{}},
+	}
+	for _, rt := range r.routes {
+		if rt.hidden {
+			continue
+		}
+		path, op := r.openAPIOperation(doc, rt)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(rt.method)] = op
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (r *Router) openAPIOperation(doc *openAPIDocument, rt route) (string, *openAPIOperation) {
+	ft := reflect.TypeOf(rt.handler)
+	op := &openAPIOperation{Responses: map[string]*openAPIResponse{}}
+
+	params := pathParamNames(rt.path)
+	parts := strings.Split(rt.path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	paramIndex := 0
+	for i := 0; i < ft.NumIn(); i++ {
+		pt := ft.In(i)
+		if injectedParamBuilder(pt) != nil {
+			continue
+		}
+		if isParamsType(pt) {
+			openAPIParams(doc, op, pt)
+			continue
+		}
+		if paramIndex < len(params) {
+			op.Parameters = append(op.Parameters, &openAPIParameter{
+				Name:     params[paramIndex],
+				In:       "path",
+				Required: true,
+				Schema:   schemaForType(doc, pt),
+			})
+			paramIndex++
+			continue
+		}
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]*openAPIMediaType{"application/json": {Schema: schemaForType(doc, pt)}},
+		}
+	}
+
+	var body reflect.Type
+	switch ft.NumOut() {
+	case 2:
+		if ft.Out(0) != reflect.TypeOf(StatusCode(0)) {
+			body = ft.Out(0)
+		}
+	case 3:
+		body = ft.Out(0)
+	}
+	if body != nil {
+		body = paginatedBodyType(body)
+	}
+	code := http.StatusOK
+	if body != nil && isRedirectType(body) {
+		// The status is only known at runtime, so document the default used by writeBody.
+		code, body = http.StatusFound, nil
+	} else if rt.method == "POST" {
+		code = http.StatusCreated
+	} else if body == nil {
+		code = http.StatusNoContent
+	}
+	response := &openAPIResponse{Description: http.StatusText(code)}
+	if body != nil && isResponseType(body) {
+		response.Content = map[string]*openAPIMediaType{"application/json": {Schema: &openAPISchema{}}}
+	} else if body != nil && isRawType(body) {
+		response.Content = map[string]*openAPIMediaType{"application/octet-stream": {Schema: &openAPISchema{Type: "string", Format: "binary"}}}
+	} else if body != nil {
+		response.Content = map[string]*openAPIMediaType{"application/json": {Schema: schemaForType(doc, body)}}
+	}
+	if op.RequestBody != nil {
+		for _, media := range op.RequestBody.Content {
+			media.Examples = openAPIExamples(rt.requestExamples)
+		}
+	}
+	for _, media := range response.Content {
+		media.Examples = openAPIExamples(rt.responseExamples)
+	}
+	op.Responses[strconv.Itoa(code)] = response
+	op.Responses["default"] = &openAPIResponse{
+		Description: "Error",
+		Content: map[string]*openAPIMediaType{
+			"application/json": {Schema: schemaForType(doc, reflect.TypeOf(ErrorResponse{}))},
+		},
+	}
+	return strings.Join(parts, "/"), op
+}
+
+func openAPIExamples(examples map[string]interface{}) map[string]*openAPIExample {
+	if len(examples) == 0 {
+		return nil
+	}
+	out := map[string]*openAPIExample{}
+	for name, value := range examples {
+		out[name] = &openAPIExample{Value: value}
+	}
+	return out
+}
+
+// openAPIParams adds the parameters and request body described by a struct embedding Params.
+func openAPIParams(doc *openAPIDocument, op *openAPIOperation, t reflect.Type) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		for _, in := range []string{"path", "query", "header"} {
+			if name, ok := field.Tag.Lookup(in); ok {
+				op.Parameters = append(op.Parameters, &openAPIParameter{
+					Name:     name,
+					In:       in,
+					Required: in == "path",
+					Schema:   schemaForType(doc, field.Type),
+				})
+			}
+		}
+		if _, ok := field.Tag.Lookup("body"); ok {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  map[string]*openAPIMediaType{"application/json": {Schema: schemaForType(doc, field.Type)}},
+			}
+		}
+	}
+}
+
+// schemaForType maps a Go type to a JSON schema, registering named structs as components.
+func schemaForType(doc *openAPIDocument, t reflect.Type) *openAPISchema {
+	if t == jsonArrayType {
+		return &openAPISchema{Type: "array", Items: &openAPISchema{}}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case bigIntType.Elem():
+		return &openAPISchema{Type: "integer"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: schemaForType(doc, t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: schemaForType(doc, t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(doc, t)
+		}
+		ref := &openAPISchema{Ref: "#/components/schemas/" + t.Name()}
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// Register before recursing so self-referential types terminate.
+			doc.Components.Schemas[t.Name()] = &openAPISchema{}
+			doc.Components.Schemas[t.Name()] = structSchema(doc, t)
+		}
+		return ref
+	}
+	return &openAPISchema{}
+}
+
+func structSchema(doc *openAPIDocument, t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		schema.Properties[name] = schemaForType(doc, field.Type)
+	}
+	return schema
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIUser struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags,omitempty"`
+	Secret string   `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(ctx context.Context, id int64) (*openAPIUser, error) { return nil, nil })
+	r.Post("/users", func(user *openAPIUser) (*openAPIUser, error) { return nil, nil })
+	r.Put("/users/:id/score", func(id int64, score float32) error { return nil })
+
+	data, err := r.OpenAPI()
+	require.NoError(t, err)
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(data, &doc)
+	require.NoError(t, err)
+	require.Equal(t, "3.0.3", doc["openapi"])
+
+	paths := doc["paths"].(map[string]interface{})
+	get := paths["/users/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	require.Equal(t, []interface{}{map[string]interface{}{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]interface{}{"type": "integer", "format": "int64"},
+	}}, get["parameters"])
+	responses := get["responses"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/openAPIUser"},
+		responses["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"])
+
+	post := paths["/users"].(map[string]interface{})["post"].(map[string]interface{})
+	require.Contains(t, post["responses"], "201")
+	require.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/openAPIUser"},
+		post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"])
+
+	put := paths["/users/{id}/score"].(map[string]interface{})["put"].(map[string]interface{})
+	require.Contains(t, put["responses"], "204")
+	require.Equal(t, map[string]interface{}{"type": "number", "format": "float"},
+		put["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"])
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":   map[string]interface{}{"type": "integer", "format": "int64"},
+			"name": map[string]interface{}{"type": "string"},
+			"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}, schemas["openAPIUser"])
+	require.Contains(t, schemas, "ErrorResponse")
+}
+
+func TestOpenAPIExamples(t *testing.T) {
+	r := New()
+	r.Post("/users", func(user *openAPIUser) (*openAPIUser, error) { return nil, nil },
+		WithRequestExample("bob", &openAPIUser{Name: "Bob"}),
+		WithResponseExample("bob", &openAPIUser{ID: 1, Name: "Bob"}))
+
+	data, err := r.OpenAPI()
+	require.NoError(t, err)
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(data, &doc)
+	require.NoError(t, err)
+
+	post := doc["paths"].(map[string]interface{})["/users"].(map[string]interface{})["post"].(map[string]interface{})
+	request := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"bob": map[string]interface{}{"value": map[string]interface{}{"id": 0.0, "name": "Bob"}},
+	}, request["examples"])
+	response := post["responses"].(map[string]interface{})["201"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"bob": map[string]interface{}{"value": map[string]interface{}{"id": 1.0, "name": "Bob"}},
+	}, response["examples"])
+}
+
+func TestOpenAPIPaginated(t *testing.T) {
+	r := New()
+	r.Get("/users", func() (*Paginated[string], error) { return nil, nil })
+
+	data, err := r.OpenAPI()
+	require.NoError(t, err)
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(data, &doc)
+	require.NoError(t, err)
+
+	get := doc["paths"].(map[string]interface{})["/users"].(map[string]interface{})["get"].(map[string]interface{})
+	response := get["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	require.Equal(t, "array", response["schema"].(map[string]interface{})["type"])
+}
+
+func TestOpenAPIRedirect(t *testing.T) {
+	r := New()
+	r.Get("/latest", func() (*Redirect, error) { return &Redirect{Location: "/v2"}, nil })
+
+	data, err := r.OpenAPI()
+	require.NoError(t, err)
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(data, &doc)
+	require.NoError(t, err)
+
+	get := doc["paths"].(map[string]interface{})["/latest"].(map[string]interface{})["get"].(map[string]interface{})
+	responses := get["responses"].(map[string]interface{})
+	require.Contains(t, responses, "302")
+	require.NotContains(t, responses, "303")
+}
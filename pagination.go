@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// CursorParam is the query parameter that Link headers emitted for Paginated responses
+// pass page cursors in.
+const CursorParam = "cursor"
+
+// Paginated may be returned as the body of a handler to respond with a single page of a
+// collection.
+//
+// Only Items are encoded in the body. The cursors of the neighbouring pages are emitted
+// in an RFC 5988 Link header, as links to the request URL with the "cursor" query
+// parameter set. Empty cursors, eg. Next on the last page, are omitted.
+//
+// eg.
+//
+//	type ListUsers struct {
+//		rest.Params
+//		Cursor string `query:"cursor"`
+//	}
+//
+//	r.Get("/users", func(params *ListUsers) (*rest.Paginated[*User], error) {
+//		users, next := db.ListUsers(params.Cursor)
+//		return &rest.Paginated[*User]{Items: users, Next: next}, nil
+//	})
+type Paginated[T any] struct {
+	Items []T
+
+	First string
+	Prev  string
+	Next  string
+	Last  string
+}
+
+func (p Paginated[T]) paginate(links *LinkBuilder) interface{} {
+	links.Add("first", p.First).Add("prev", p.Prev).Add("next", p.Next).Add("last", p.Last)
+	if p.Items == nil {
+		return []T{}
+	}
+	return p.Items
+}
+
+// paginated is implemented by every instantiation of Paginated.
+type paginated interface {
+	// paginate adds links to the neighbouring pages and returns the body to encode.
+	paginate(links *LinkBuilder) interface{}
+}
+
+var paginatedType = reflect.TypeOf((*paginated)(nil)).Elem()
+
+func asPaginated(body interface{}) paginated {
+	page, ok := body.(paginated)
+	if !ok {
+		return nil
+	}
+	if v := reflect.ValueOf(body); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	return page
+}
+
+// paginatedBodyType returns the type of the body encoded for t, which is the type of
+// Items for a Paginated.
+func paginatedBodyType(t reflect.Type) reflect.Type {
+	if !t.Implements(paginatedType) {
+		return t
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	field, _ := t.FieldByName("Items")
+	return field.Type
+}
+
+// A LinkBuilder builds an RFC 5988 Link header of pages of a collection.
+//
+// eg.
+//
+//	links := rest.NewLinkBuilder(req.URL, "page").Add("next", "3").Add("prev", "1")
+//	w.Header().Set("Link", links.String())
+type LinkBuilder struct {
+	base  *url.URL
+	param string
+	links []string
+}
+
+// NewLinkBuilder creates a LinkBuilder linking to pages of base, by setting the query
+// parameter param to the cursor of each page.
+func NewLinkBuilder(base *url.URL, param string) *LinkBuilder {
+	return &LinkBuilder{base: base, param: param}
+}
+
+// Add a link with relation rel to the page at cursor.
+//
+// Empty cursors are ignored, eg. there is no next page.
+func (l *LinkBuilder) Add(rel, cursor string) *LinkBuilder {
+	if cursor == "" {
+		return l
+	}
+	u := *l.base
+	query := u.Query()
+	query.Set(l.param, cursor)
+	u.RawQuery = query.Encode()
+	l.links = append(l.links, fmt.Sprintf("<%s>; rel=%q", u.String(), rel))
+	return l
+}
+
+// String returns the value of the Link header, or "" if no links were added.
+func (l *LinkBuilder) String() string {
+	return strings.Join(l.links, ", ")
+}
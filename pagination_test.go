@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginated(t *testing.T) {
+	type listParams struct {
+		Params
+		Cursor string `query:"cursor"`
+	}
+	r := New()
+	r.Get("/users", func(params *listParams) (*Paginated[string], error) {
+		if params.Cursor == "" {
+			return &Paginated[string]{Items: []string{"alice", "bob"}, Next: "2", Last: "3"}, nil
+		}
+		return &Paginated[string]{Items: []string{"carol"}, First: "1", Prev: "1", Next: "3", Last: "3"}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := []string{}
+	resp := getAndDecode(t, server, "/users?limit=2", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"alice", "bob"}, actual)
+	require.Equal(t, `</users?cursor=2&limit=2>; rel="next", </users?cursor=3&limit=2>; rel="last"`, resp.Header.Get("Link"))
+
+	resp = getAndDecode(t, server, "/users?cursor=2&limit=2", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"carol"}, actual)
+	require.Equal(t, ""+
+		`</users?cursor=1&limit=2>; rel="first", `+
+		`</users?cursor=1&limit=2>; rel="prev", `+
+		`</users?cursor=3&limit=2>; rel="next", `+
+		`</users?cursor=3&limit=2>; rel="last"`,
+		resp.Header.Get("Link"))
+}
+
+func TestPaginatedEmpty(t *testing.T) {
+	r := New()
+	r.Get("/users", func() (Paginated[string], error) {
+		return Paginated[string]{}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, body := getRaw(t, server, "/users")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "[]\n", body)
+	require.Empty(t, resp.Header.Values("Link"))
+}
+
+func TestLinkBuilder(t *testing.T) {
+	base, err := url.Parse("https://example.com/items?sort=name&page=1")
+	require.NoError(t, err)
+	links := NewLinkBuilder(base, "page").Add("prev", "").Add("next", "2").Add("last", "10")
+	require.Equal(t, ""+
+		`<https://example.com/items?page=2&sort=name>; rel="next", `+
+		`<https://example.com/items?page=10&sort=name>; rel="last"`,
+		links.String())
+	require.Equal(t, "https://example.com/items?sort=name&page=1", base.String())
+}
@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Params may be embedded in a struct to declare that struct as the single input of a
+// handler, aggregating every source of request data.
+//
+// Fields of type context.Context, *http.Request and http.Header are injected as they
+// would be for handler parameters. Other fields are populated according to their tag:
+//
+//	path:"<name>"    - the named path parameter
+//	query:"<name>"   - the named query parameter, or the zero value if it is missing
+//	header:"<name>"  - the named request header, or the zero value if it is missing
+//	body:""          - the decoded request body
+//
+// Query fields may also carry an enum:"<value>,..." tag restricting them to a set of
+// allowed values. Values are matched case-insensitively and bound as the allowed value,
+// with any other value rejected with a 400.
+//
+// eg.
+//
+//	type UpdateUserParams struct {
+//		rest.Params
+//		Ctx       context.Context
+//		ID        int    `path:"id"`
+//		DryRun    bool   `query:"dry_run"`
+//		RequestID string `header:"X-Request-Id"`
+//		User      *User  `body:""`
+//	}
+//
+//	r.Put("/users/:id", func(params *UpdateUserParams) error { ... })
+type Params struct{}
+
+var paramsType = reflect.TypeOf(Params{})
+
+// isParamsType returns true if t is a struct, or pointer to a struct, embedding Params.
+func isParamsType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Anonymous && field.Type == paramsType {
+			return true
+		}
+	}
+	return false
+}
+
+type fieldBuilder struct {
+	index   int
+	builder paramBuilder
+}
+
+// paramsBuilder returns a paramBuilder that populates a struct embedding Params.
+func (r *Router) paramsBuilder(rt route, pt reflect.Type) paramBuilder {
+	isPtr := pt.Kind() == reflect.Ptr
+	if isPtr {
+		pt = pt.Elem()
+	}
+	pathParams := map[string]bool{}
+	for _, name := range pathParamNames(rt.path) {
+		pathParams[name] = true
+	}
+	fields := []fieldBuilder{}
+	for i := 0; i < pt.NumField(); i++ {
+		field := pt.Field(i)
+		if field.PkgPath != "" || (field.Anonymous && field.Type == paramsType) {
+			continue
+		}
+		var builder paramBuilder
+		if name, ok := field.Tag.Lookup("path"); ok {
+			if !pathParams[name] {
+				panic("field " + field.Name + " of " + pt.String() + " refers to unknown path parameter " + name + " in " + rt.path)
+			}
+			builder = r.pathParamBuilder(field.Type, name, rt.paramValue(name))
+		} else if name, ok := field.Tag.Lookup("query"); ok {
+			builder = r.pathParamBuilder(field.Type, name, queryValue(name))
+			if enum, ok := field.Tag.Lookup("enum"); ok {
+				allowed := strings.Split(enum, ",")
+				builder = enumParam(name, allowed, queryValue(name), r.pathParamBuilder(field.Type, name, enumValue(allowed, queryValue(name))))
+			}
+			builder = optionalParam(field.Type, queryValue(name), builder)
+		} else if name, ok := field.Tag.Lookup("header"); ok {
+			builder = optionalParam(field.Type, headerValue(name), r.pathParamBuilder(field.Type, name, headerValue(name)))
+		} else if _, ok := field.Tag.Lookup("body"); ok {
+			builder = r.bodyBuilder(rt, field.Type)
+		} else if builder = injectedParamBuilder(field.Type); builder == nil {
+			panic("field " + field.Name + " of " + pt.String() + " must have a path, query, header or body tag")
+		}
+		fields = append(fields, fieldBuilder{index: i, builder: builder})
+	}
+	return func(req *http.Request) (reflect.Value, error) {
+		v := reflect.New(pt)
+		for _, field := range fields {
+			value, err := field.builder(req)
+			if err != nil {
+				return v, err
+			}
+			// Scalar parameters are parsed as their underlying kind, eg. string for a
+			// named string type.
+			v.Elem().Field(field.index).Set(value.Convert(pt.Field(field.index).Type))
+		}
+		if !isPtr {
+			v = v.Elem()
+		}
+		return v, nil
+	}
+}
+
+func queryValue(name string) func(req *http.Request) string {
+	return func(req *http.Request) string { return req.URL.Query().Get(name) }
+}
+
+func headerValue(name string) func(req *http.Request) string {
+	return func(req *http.Request) string { return req.Header.Get(name) }
+}
+
+// enumValue wraps value to return the allowed value matching it case-insensitively.
+func enumValue(allowed []string, value func(req *http.Request) string) func(req *http.Request) string {
+	return func(req *http.Request) string {
+		v := value(req)
+		for _, a := range allowed {
+			if strings.EqualFold(a, v) {
+				return a
+			}
+		}
+		return v
+	}
+}
+
+// enumParam wraps builder to reject values that do not case-insensitively match one of allowed.
+func enumParam(name string, allowed []string, value func(req *http.Request) string, builder paramBuilder) paramBuilder {
+	return func(req *http.Request) (reflect.Value, error) {
+		v := value(req)
+		for _, a := range allowed {
+			if strings.EqualFold(a, v) {
+				return builder(req)
+			}
+		}
+		return reflect.Value{}, Errorf(http.StatusBadRequest, "invalid value %q for %s, must be one of %s", v, name, strings.Join(allowed, ", "))
+	}
+}
+
+// optionalParam wraps builder to return the zero value of t if value is empty.
+func optionalParam(t reflect.Type, value func(req *http.Request) string, builder paramBuilder) paramBuilder {
+	return func(req *http.Request) (reflect.Value, error) {
+		if value(req) == "" {
+			return reflect.Zero(t), nil
+		}
+		return builder(req)
+	}
+}
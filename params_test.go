@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type paramsUser struct {
+	Name string
+}
+
+type updateUserParams struct {
+	Params
+	Ctx       context.Context
+	Headers   http.Header
+	ID        int         `path:"id"`
+	DryRun    bool        `query:"dry_run"`
+	Limit     int         `query:"limit"`
+	RequestID string      `header:"X-Request-Id"`
+	User      *paramsUser `body:""`
+}
+
+func TestParams(t *testing.T) {
+	var actual *updateUserParams
+	r := New()
+	r.Put("/users/:id", func(params *updateUserParams) error {
+		actual = params
+		return nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, err := http.NewRequest("PUT", server.URL+"/users/42?dry_run=true", strings.NewReader(`{"Name": "Bob"}`))
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "abc")
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.NotNil(t, actual.Ctx)
+	require.Equal(t, "abc", actual.Headers.Get("X-Request-Id"))
+	require.Equal(t, 42, actual.ID)
+	require.True(t, actual.DryRun)
+	require.Equal(t, 0, actual.Limit)
+	require.Equal(t, "abc", actual.RequestID)
+	require.Equal(t, &paramsUser{Name: "Bob"}, actual.User)
+
+	spec, err := r.OpenAPI()
+	require.NoError(t, err)
+	require.Contains(t, string(spec), `"in": "query"`)
+	require.Contains(t, string(spec), `"name": "X-Request-Id"`)
+	require.Contains(t, string(spec), `"$ref": "#/components/schemas/paramsUser"`)
+}
+
+type paramsUserID int
+
+type paramsName string
+
+func TestParamsNamedTypes(t *testing.T) {
+	type getUserParams struct {
+		Params
+		ID     paramsUserID `path:"id"`
+		Format paramsName   `query:"format"`
+		Agent  paramsName   `header:"User-Agent"`
+	}
+	r := New()
+	r.Get("/users/:id", func(params *getUserParams) (*getUserParams, error) { return params, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL+"/users/42?format=full", nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "test")
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	actual := &getUserParams{}
+	err = json.NewDecoder(resp.Body).Decode(actual)
+	require.NoError(t, err)
+	require.Equal(t, &getUserParams{ID: 42, Format: "full", Agent: "test"}, actual)
+}
+
+func TestParamsInvalid(t *testing.T) {
+	type untagged struct {
+		Params
+		ID int
+	}
+	type unknownPath struct {
+		Params
+		ID int `path:"id"`
+	}
+	r := New()
+	require.Panics(t, func() { r.Get("/:id", func(params untagged) error { return nil }) })
+	require.Panics(t, func() { r.Get("/", func(params unknownPath) error { return nil }) })
+}
+
+func TestParamsEnum(t *testing.T) {
+	type listParams struct {
+		Params
+		Order string `query:"order" enum:"asc,desc"`
+	}
+	r := New()
+	r.Get("/users", func(params listParams) (string, error) { return params.Order, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/users?order=DeSc", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "desc", actual)
+
+	errResp := &ErrorResponse{}
+	resp = getAndDecode(t, server, "/users?order=random", errResp)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Equal(t, `invalid value "random" for order, must be one of asc, desc`, errResp.Message)
+}
+
+type paramsSortOrder string
+
+func TestParamsEnumNamedType(t *testing.T) {
+	type listParams struct {
+		Params
+		Order paramsSortOrder `query:"order" enum:"asc,desc"`
+	}
+	r := New()
+	r.Get("/users", func(params listParams) (paramsSortOrder, error) { return params.Order, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var actual paramsSortOrder
+	resp := getAndDecode(t, server, "/users?order=ASC", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, paramsSortOrder("asc"), actual)
+
+	resp = getAndDecode(t, server, "/users?order=random", nil)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestParamsLocaleNumbers(t *testing.T) {
+	type priceParams struct {
+		Params
+		ID    int     `path:"id"`
+		Price float64 `query:"price"`
+	}
+	r := New(WithLocaleNumbers(",", "."))
+	r.Get("/items/:id", func(params priceParams) (*priceParams, error) { return &params, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &priceParams{}
+	resp := getAndDecode(t, server, "/items/1.000?price=1.234,56", actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, &priceParams{ID: 1000, Price: 1234.56}, actual)
+}
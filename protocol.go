@@ -2,7 +2,10 @@ package rest
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // StatusCode is a type that can be returned by a handler to explicitly set a status code.
@@ -61,3 +64,84 @@ func Error(code int, msg string) error { return &ErrorResponse{Status: code, Mes
 func Errorf(code int, format string, args ...interface{}) error {
 	return &ErrorResponse{Status: code, Message: fmt.Sprintf(format, args...)}
 }
+
+// TypedError is an error that is returned to the client with a structured body, rather
+// than as an ErrorResponse.
+//
+// eg.
+//
+//	return nil, rest.NewTypedError(http.StatusBadRequest, &ValidationErrors{Fields: fields})
+type TypedError struct {
+	Status int
+	Body   interface{}
+}
+
+func (t *TypedError) Error() string { return fmt.Sprintf("%d: %v", t.Status, t.Body) }
+
+// NewTypedError creates a new HTTP error response with a structured body.
+func NewTypedError(code int, body interface{}) error { return &TypedError{Status: code, Body: body} }
+
+// UnauthorizedError is a 401 error that includes an authentication challenge, which is
+// returned to the client in the WWW-Authenticate header.
+//
+// eg.
+//
+//	return nil, rest.Unauthorized(`Bearer realm="api"`, "token expired")
+type UnauthorizedError struct {
+	Challenge string
+	Message   string
+}
+
+func (u *UnauthorizedError) Error() string {
+	return fmt.Sprintf("%d: %s", http.StatusUnauthorized, u.Message)
+}
+
+// Unwrap returns the ErrorResponse sent as the body of the 401.
+func (u *UnauthorizedError) Unwrap() error {
+	return &ErrorResponse{Status: http.StatusUnauthorized, Message: u.Message}
+}
+
+// Unauthorized creates a new 401 error with an authentication challenge.
+func Unauthorized(challenge, message string) error {
+	return &UnauthorizedError{Challenge: challenge, Message: message}
+}
+
+// TooManyRequestsError is a 429 error that reports the client's rate limit, which is
+// returned to the client in X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset
+// and Retry-After headers.
+//
+// eg.
+//
+//	return nil, rest.TooManyRequests(100, 0, window.End)
+type TooManyRequestsError struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (t *TooManyRequestsError) Error() string {
+	return fmt.Sprintf("%d: rate limit of %d exceeded", http.StatusTooManyRequests, t.Limit)
+}
+
+// Unwrap returns the ErrorResponse sent as the body of the 429.
+func (t *TooManyRequestsError) Unwrap() error {
+	return Errorf(http.StatusTooManyRequests, "rate limit of %d exceeded", t.Limit)
+}
+
+// TooManyRequests creates a new 429 error for a client that has exceeded its rate limit,
+// which resets at reset.
+func TooManyRequests(limit, remaining int, reset time.Time) error {
+	return &TooManyRequestsError{Limit: limit, Remaining: remaining, Reset: reset}
+}
+
+// setHeaders sets the rate limit headers for the error.
+func (t *TooManyRequestsError) setHeaders(header http.Header) {
+	retryAfter := int64(math.Ceil(time.Until(t.Reset).Seconds()))
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	header.Set("X-RateLimit-Limit", strconv.Itoa(t.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(t.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(t.Reset.Unix(), 10))
+	header.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+}
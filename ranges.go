@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithRecordRanges declares that a route supports requests for a range of the records in
+// its response, eg. "Range: records=0-9".
+//
+// The handler returns all records as a slice, from which the Router selects the requested
+// range and responds with a 206 and a Content-Range header of the form
+// "records <first>-<last>/<total>". Requests without a Range header receive every record.
+// Only a single range is supported.
+func WithRecordRanges() RouteOption {
+	return func(r *route) {
+		r.recordRanges = true
+	}
+}
+
+// recordRange selects the range of records in body requested by req.
+func recordRange(req *http.Request, w http.ResponseWriter, code int, body interface{}) (int, interface{}, error) {
+	v := reflect.ValueOf(body)
+	if v.Kind() != reflect.Slice {
+		return code, body, nil
+	}
+	w.Header().Set("Accept-Ranges", "records")
+	spec := req.Header.Get("Range")
+	if !strings.HasPrefix(spec, "records=") {
+		return code, body, nil
+	}
+	total := v.Len()
+	first, last, ok := parseRecordRange(strings.TrimPrefix(spec, "records="), total)
+	if !ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("records */%d", total))
+		return 0, nil, Errorf(http.StatusRequestedRangeNotSatisfiable, "invalid record range %q", spec)
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("records %d-%d/%d", first, last, total))
+	if code == 0 {
+		code = http.StatusPartialContent
+	}
+	return code, v.Slice(first, last+1).Interface(), nil
+}
+
+// parseRecordRange parses a range of the form "<first>-<last>", "<first>-" or "-<count>"
+// into inclusive bounds within total records.
+func parseRecordRange(spec string, total int) (first, last int, ok bool) {
+	parts := strings.Split(strings.TrimSpace(spec), "-")
+	if len(parts) != 2 || total == 0 {
+		return 0, 0, false
+	}
+	var err error
+	switch {
+	case parts[0] == "":
+		var count int
+		if count, err = strconv.Atoi(parts[1]); err != nil || count <= 0 {
+			return 0, 0, false
+		}
+		first, last = total-count, total-1
+		if first < 0 {
+			first = 0
+		}
+		return first, last, true
+	case parts[1] == "":
+		last = total - 1
+	default:
+		if last, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, false
+		}
+	}
+	if first, err = strconv.Atoi(parts[0]); err != nil || first < 0 || first >= total || last < first {
+		return 0, 0, false
+	}
+	if last >= total {
+		last = total - 1
+	}
+	return first, last, true
+}
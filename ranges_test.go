@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordRanges(t *testing.T) {
+	r := New()
+	r.Get("/numbers", func() ([]int, error) {
+		numbers := []int{}
+		for i := 0; i < 100; i++ {
+			numbers = append(numbers, i)
+		}
+		return numbers, nil
+	}, WithRecordRanges())
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(t *testing.T, rng string, v interface{}) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+"/numbers", nil)
+		require.NoError(t, err)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		err = json.NewDecoder(resp.Body).Decode(v)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Range", func(t *testing.T) {
+		actual := []int{}
+		resp := get(t, "records=10-14", &actual)
+		require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		require.Equal(t, "records 10-14/100", resp.Header.Get("Content-Range"))
+		require.Equal(t, []int{10, 11, 12, 13, 14}, actual)
+	})
+
+	t.Run("Suffix", func(t *testing.T) {
+		actual := []int{}
+		resp := get(t, "records=-2", &actual)
+		require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		require.Equal(t, "records 98-99/100", resp.Header.Get("Content-Range"))
+		require.Equal(t, []int{98, 99}, actual)
+	})
+
+	t.Run("NoRange", func(t *testing.T) {
+		actual := []int{}
+		resp := get(t, "", &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "records", resp.Header.Get("Accept-Ranges"))
+		require.Len(t, actual, 100)
+	})
+
+	t.Run("Unsatisfiable", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := get(t, "records=200-300", actual)
+		require.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+		require.Equal(t, "records */100", resp.Header.Get("Content-Range"))
+	})
+}
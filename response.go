@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// Raw is a response body that is copied directly to the client rather than being
+// encoded by the Protocol.
+//
+// Handlers may also return any io.Reader as their body. If ContentType is empty it
+// is detected from the first 512 bytes of the body with http.DetectContentType. If
+// Body implements io.Closer it will be closed once the response has been written.
+type Raw struct {
+	ContentType string
+	Body        io.Reader
+}
+
+// Response may be returned as the body of a handler to explicitly set response headers
+// and, optionally, the status code.
+//
+// Body is encoded as if it had been returned directly by the handler.
+type Response struct {
+	Headers http.Header
+	Body    interface{}
+	Status  StatusCode
+}
+
+// EmptyObject may be returned as the body of a handler to respond with an empty object,
+// eg. "{}" for JSON, rather than with a 204 and no body.
+//
+// eg.
+//
+//	r.Put("/users/:id", func(id int, user *User) (rest.EmptyObject, error) { ... })
+type EmptyObject struct{}
+
+// Redirect may be returned as the body of a handler to redirect the client to Location.
+//
+// Status defaults to 302 Found. See SeeOther.
+type Redirect struct {
+	Location string
+	Status   StatusCode
+}
+
+// SeeOther redirects the client to location with a 303, eg. after a POST so that the
+// client follows with a GET rather than resubmitting (Post/Redirect/Get).
+//
+// eg.
+//
+//	r.Post("/orders", func(order *Order) (*rest.Redirect, error) {
+//		...
+//		return rest.SeeOther("/orders/" + id), nil
+//	})
+func SeeOther(location string) *Redirect {
+	return &Redirect{Location: location, Status: http.StatusSeeOther}
+}
+
+var (
+	rawType      = reflect.TypeOf(Raw{})
+	readerType   = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	responseType = reflect.TypeOf(Response{})
+	redirectType = reflect.TypeOf(Redirect{})
+)
+
+func isResponseType(t reflect.Type) bool {
+	return t == responseType || t == reflect.PtrTo(responseType)
+}
+
+func asResponse(body interface{}) *Response {
+	switch body := body.(type) {
+	case Response:
+		return &body
+	case *Response:
+		return body
+	}
+	return nil
+}
+
+func isRedirectType(t reflect.Type) bool {
+	return t == redirectType || t == reflect.PtrTo(redirectType)
+}
+
+func asRedirect(body interface{}) *Redirect {
+	switch body := body.(type) {
+	case Redirect:
+		return &body
+	case *Redirect:
+		return body
+	}
+	return nil
+}
+
+func isRawType(t reflect.Type) bool {
+	return t == rawType || t == reflect.PtrTo(rawType) || t.Implements(readerType)
+}
+
+func asRaw(body interface{}) *Raw {
+	switch body := body.(type) {
+	case Raw:
+		return &body
+	case *Raw:
+		return body
+	case io.Reader:
+		return &Raw{Body: body}
+	}
+	return nil
+}
+
+func writeRaw(w http.ResponseWriter, code int, raw *Raw) error {
+	if closer, ok := raw.Body.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if bw, ok := w.(*bufferedResponseWriter); ok {
+		bw.raw = true
+	}
+	if code == 0 {
+		code = http.StatusOK
+	}
+	body := raw.Body
+	contentType := raw.ContentType
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(body, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		contentType = http.DetectContentType(head[:n])
+		body = io.MultiReader(bytes.NewReader(head[:n]), body)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	// Flush headers immediately so the body is streamed with chunked encoding, which
+	// also allows trailers to be sent.
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	_, err := io.Copy(w, body)
+	return err
+}
@@ -1,11 +1,23 @@
 package rest
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
 	"net/http"
+	"path"
 	"reflect"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/bmizerany/pat"
 )
@@ -14,6 +26,7 @@ var (
 	errorType   = reflect.TypeOf((*error)(nil)).Elem()
 	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 	requestType = reflect.TypeOf(&http.Request{})
+	headerType  = reflect.TypeOf(http.Header{})
 	intType     = reflect.TypeOf(int(0))
 	int8Type    = reflect.TypeOf(int8(0))
 	int16Type   = reflect.TypeOf(int16(0))
@@ -26,33 +39,197 @@ var (
 	uint64Type  = reflect.TypeOf(uint64(0))
 	float32Type = reflect.TypeOf(float32(0))
 	float64Type = reflect.TypeOf(float64(0))
+	bigIntType  = reflect.TypeOf(&big.Int{})
 )
 
 type route struct {
 	method  string
 	path    string
 	handler interface{}
+	hidden  bool
+	scopes  []string
+	serve   http.HandlerFunc
+
+	recordRanges    bool
+	paramTransforms map[string]func(string) string
+	cache           *resultCache
+	contentTypes    []string
+	aliases         []string
+	defaultBody     func() interface{}
+	bodySchema      []byte
+
+	requestExamples  map[string]interface{}
+	responseExamples map[string]interface{}
+}
+
+// paramValue returns a function that extracts the named path parameter from a request,
+// applying any transform registered for it.
+func (r route) paramValue(name string) func(req *http.Request) string {
+	transform := r.paramTransforms[name]
+	return func(req *http.Request) string {
+		value := req.URL.Query().Get(":" + name)
+		if transform != nil {
+			value = transform(value)
+		}
+		return value
+	}
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method  string
+	Path    string
+	Handler interface{}
+}
+
+// A RouteOption configures a single route.
+type RouteOption func(r *route)
+
+// WithHidden excludes a route from introspection via Routes() and OpenAPI().
+//
+// The route is still served as normal.
+func WithHidden() RouteOption {
+	return func(r *route) {
+		r.hidden = true
+	}
+}
+
+// WithParamTransform transforms the value of the named path parameter before it is
+// passed to the handler, eg. to normalise case.
+func WithParamTransform(name string, transform func(string) string) RouteOption {
+	return func(r *route) {
+		if r.paramTransforms == nil {
+			r.paramTransforms = map[string]func(string) string{}
+		}
+		r.paramTransforms[name] = transform
+	}
+}
+
+// WithScopes declares the scopes a request must be authorized for to access a route.
+//
+// Scopes are checked by the Router's authorizer, see WithAuthorizer.
+func WithScopes(scopes ...string) RouteOption {
+	return func(r *route) {
+		r.scopes = append(r.scopes, scopes...)
+	}
+}
+
+// WithAliases serves a route under additional paths, eg. "/members/:id" for "/users/:id".
+//
+// Aliases must have the same path parameters as the route.
+func WithAliases(paths ...string) RouteOption {
+	return func(r *route) {
+		r.aliases = append(r.aliases, paths...)
+	}
+}
+
+// WithDefaultBody sets a function supplying the body passed to a route's handler when the
+// request body is empty, rather than decoding the empty body.
+//
+// supplier must return a value of the handler's body type, or a pointer to it.
+func WithDefaultBody(supplier func() interface{}) RouteOption {
+	return func(r *route) {
+		r.defaultBody = supplier
+	}
+}
+
+// WithContentTypes restricts the request bodies accepted by a route to the given media
+// types, eg. "image/png".
+//
+// Requests with any other Content-Type are rejected with a 415.
+func WithContentTypes(mediaTypes ...string) RouteOption {
+	return func(r *route) {
+		r.contentTypes = append(r.contentTypes, mediaTypes...)
+	}
 }
 
 type paramBuilder func(r *http.Request) (reflect.Value, error)
 
+// Validator may be implemented by request bodies to validate themselves after decoding.
+//
+// If Validate returns an error the handler will not be called and the error will be returned
+// to the client, with a status of 422 unless the error is an *ErrorResponse.
+type Validator interface {
+	Validate() error
+}
+
 // A Router maps URLs to functions using the following rules.
 //
 // The first parameter may be neither or one of type context.Context or *http.Request.
-// All path variables are then mapped to subsequent function parameters.
+// All path variables are then mapped to subsequent function parameters. Parameters of
+// type http.Header may appear anywhere and receive the request headers.
+// Alternatively, a handler may accept a single struct embedding Params, see Params for details.
 //
 // Finally, if the routes method is a POST, PUT or PATCH, the request body will be decoded
 // into the last parameter via ServerProtocol.DecodeClientRequest().
 //
 // The return type of the function may be either (error), (<body>, error), (StatusCode, error)
-// or (<body>, StatusCode, error).
-// If a <body> is returned, it is encoded using ServerProtocol.EncodeServerResponse().
+// or (<body>, StatusCode, error). A <body> of type Response may be used to also set
+// response headers, and a <body> implementing http.Handler will be delegated the request.
+// A <body> of type func() (interface{}, error) is evaluated lazily, immediately before encoding.
+// A <body> of type Paginated encodes its Items and links to neighbouring pages in a Link header.
+// If a <body> is returned, it is encoded using ServerProtocol.EncodeServerResponse(), unless
+// it is an io.Reader or Raw, in which case it is copied directly to the client.
 type Router struct {
-	router   *pat.PatternServeMux
-	protocol Protocol
-	routes   []route
+	router       *pat.PatternServeMux
+	protocol     Protocol
+	routes       []route
+	mounts       map[string]bool
+	frozen       bool
+	recover      bool
+	errorLogger  func(req *http.Request, err error)
+	errorMappers []func(err error) (int, bool)
+	wrapErrors   bool
+	buffered     bool
+	oneOfs       map[reflect.Type]*oneOf
+	errorDetail  ErrorDetailMode
+	accept       acceptPolicy
+	extensions   map[string]Protocol
+	validator    SchemaValidator
+	authorizer   func(ctx context.Context, scopes []string) error
+	singleflight *singleflight
+	transcoders  map[string]Transcoder
+	values       []contextValue
+	encodeStatus int
+	mergeQuery   Precedence
+	requestDump  *requestDumper
+	maxBodySize  int64
+	numberFormat *strings.Replacer
+	bodyDecoders map[reflect.Type]func(req *http.Request, v interface{}) error
+
+	caseInsensitive bool
+	caseRedirect    bool
+	sparseFieldsets bool
+	methodTimeouts  map[string]time.Duration
+	maxEncodeDepth  int
+	middleware      []func(http.Handler) http.Handler
+	handler         http.Handler
 }
 
+type contextValue struct {
+	key   interface{}
+	value interface{}
+}
+
+type acceptPolicy int
+
+const (
+	acceptAny acceptPolicy = iota
+	acceptRequired
+	acceptRequiredNoWildcard
+)
+
+// ErrorDetailMode controls how much detail about server errors is returned to clients.
+type ErrorDetailMode int
+
+const (
+	// ErrorDetailDevelopment returns all error messages to clients verbatim. This is the default.
+	ErrorDetailDevelopment ErrorDetailMode = iota
+	// ErrorDetailProduction replaces the messages of 5xx errors with a generic message and a
+	// correlation ID. The original error is passed to the error logger along with the same ID.
+	ErrorDetailProduction
+)
+
 // An Option to configure the Router.
 type Option func(r *Router)
 
@@ -63,64 +240,671 @@ func WithProtocol(protocol Protocol) Option {
 	}
 }
 
+// WithRecover controls whether panics in handlers are recovered (the default).
+//
+// Recovered panics are passed to the error logger along with a stack trace. If the
+// panic value is an *ErrorResponse, or an error recognised by an error mapper, it is
+// returned to the client, otherwise a 500 is returned. Disable this if panics are handled by other middleware.
+//
+// Panics with http.ErrAbortHandler are not recovered, so that the response is aborted.
+func WithRecover(enabled bool) Option {
+	return func(r *Router) {
+		r.recover = enabled
+	}
+}
+
+// WithErrorLogger sets a function that will be called with errors that occur while
+// processing requests, including recovered panics.
+func WithErrorLogger(logger func(req *http.Request, err error)) Option {
+	return func(r *Router) {
+		r.errorLogger = logger
+	}
+}
+
+// WithErrorMapper adds a function that maps errors returned by handlers to HTTP status codes.
+//
+// Mappers are consulted in order for any error that is not (and does not wrap) an *ErrorResponse,
+// and the first to return true determines the status. Unmapped errors result in a 500.
+//
+// eg.
+//
+//	rest.WithErrorMapper(func(err error) (int, bool) {
+//		var notFound *NotFoundError
+//		if errors.As(err, &notFound) {
+//			return http.StatusNotFound, true
+//		}
+//		return 0, false
+//	})
+func WithErrorMapper(mapper func(err error) (int, bool)) Option {
+	return func(r *Router) {
+		r.errorMappers = append(r.errorMappers, mapper)
+	}
+}
+
+// WithErrorWrapper annotates errors returned by handlers with the method and path pattern
+// of the route, eg. "get /users/:id: user not found".
+//
+// The original error is wrapped, so status codes are still derived from it.
+func WithErrorWrapper() Option {
+	return func(r *Router) {
+		r.wrapErrors = true
+	}
+}
+
+// WithChunkedTransferDisabled buffers every response in full so that it is sent with
+// a Content-Length header rather than with chunked transfer encoding.
+//
+// This is useful behind proxies that mishandle chunked encoding, at the cost of holding
+// each response in memory.
+func WithChunkedTransferDisabled() Option {
+	return func(r *Router) {
+		r.buffered = true
+	}
+}
+
+// WithErrorDetailMode sets how much detail about server errors is returned to clients.
+func WithErrorDetailMode(mode ErrorDetailMode) Option {
+	return func(r *Router) {
+		r.errorDetail = mode
+	}
+}
+
+// WithRequireAccept rejects requests without an Accept header with a 406.
+//
+// If allowWildcard is false, requests that only accept "*/*" are also rejected.
+func WithRequireAccept(allowWildcard bool) Option {
+	return func(r *Router) {
+		if allowWildcard {
+			r.accept = acceptRequired
+		} else {
+			r.accept = acceptRequiredNoWildcard
+		}
+	}
+}
+
+// WithExtensionProtocols selects the Protocol used to encode responses from the extension
+// of the request path, eg. "/users/123.xml".
+//
+// The extension is removed from the path before it is matched against routes, so the
+// same route serves all extensions. Keys include the leading period, eg. ".xml".
+func WithExtensionProtocols(protocols map[string]Protocol) Option {
+	return func(r *Router) {
+		r.extensions = protocols
+	}
+}
+
+// WithAuthorizer sets the function used to check that a request is authorized for the
+// scopes declared by a route with WithScopes.
+//
+// The authorizer is called before the handler with the request context, from which it
+// should obtain the authenticated principal's scopes. If it returns an error the request
+// is rejected with a 403, or the status of the error if it is an *ErrorResponse.
+func WithAuthorizer(authorizer func(ctx context.Context, scopes []string) error) Option {
+	return func(r *Router) {
+		r.authorizer = authorizer
+	}
+}
+
+// WithSingleflight coalesces concurrent identical GET requests, so that the handler is only
+// called once and every caller receives the same response.
+//
+// Requests are identical if they have the same path, including any extension selecting a
+// Protocol, query string and negotiated charset. Routes with scopes are never coalesced.
+// Responses must not vary on anything else, such as the Authorization header, or they may
+// be sent to the wrong caller.
+func WithSingleflight() Option {
+	return func(r *Router) {
+		r.singleflight = &singleflight{}
+	}
+}
+
+// WithValue adds a static value to the context of every request, eg. to inject dependencies
+// into handlers.
+//
+// As with context.WithValue, key should be of an unexported type to avoid collisions.
+func WithValue(key, value interface{}) Option {
+	return func(r *Router) {
+		r.values = append(r.values, contextValue{key, value})
+	}
+}
+
+// A ContextKey is a typed key for values injected into request contexts.
+//
+// eg.
+//
+//	var Database = rest.NewContextKey[*sql.DB]("database")
+//
+//	router := rest.New(Database.Inject(db))
+//	router.Get("/users/:id", func(ctx context.Context, id int) (*User, error) {
+//		db, _ := Database.From(ctx)
+//		...
+//	})
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey creates a new typed context key.
+//
+// name is only used for debugging, each key is distinct.
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// Inject value into the context of every request.
+func (k *ContextKey[T]) Inject(value T) Option {
+	return WithValue(k, value)
+}
+
+// From returns the value of the key in ctx, and whether it was present.
+func (k *ContextKey[T]) From(ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(k).(T)
+	return value, ok
+}
+
+func (k *ContextKey[T]) String() string {
+	return "rest.ContextKey(" + k.name + ")"
+}
+
+// WithEncodingErrorStatus sets the status returned when a response body can't be encoded,
+// which defaults to 500.
+//
+// This only applies to buffered responses, eg. WithChunkedTransferDisabled, as otherwise
+// the response headers will already have been sent, and to bodies rejected by
+// WithMaxEncodeDepth.
+func WithEncodingErrorStatus(code int) Option {
+	return func(r *Router) {
+		r.encodeStatus = code
+	}
+}
+
+// WithMaxBodySize limits request bodies to n bytes.
+//
+// Requests with larger bodies are rejected with a 413, including when the limit is
+// reached part way through a streaming decode such as a JSONArray.
+func WithMaxBodySize(n int64) Option {
+	return func(r *Router) {
+		r.maxBodySize = n
+	}
+}
+
+// WithBodyDecoder registers a function used to decode request bodies of type t, or of
+// pointers to t, in place of the Protocol.
+//
+// decoder is passed a pointer to a new value of type t to decode into. Decoded bodies
+// are still validated if they implement Validator.
+func WithBodyDecoder(t reflect.Type, decoder func(req *http.Request, v interface{}) error) Option {
+	return func(r *Router) {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if r.bodyDecoders == nil {
+			r.bodyDecoders = map[reflect.Type]func(req *http.Request, v interface{}) error{}
+		}
+		r.bodyDecoders[t] = decoder
+	}
+}
+
+// WithMethodTimeouts sets a timeout on the context of requests with the given methods,
+// eg. map[string]time.Duration{"GET": 2 * time.Second, "POST": 10 * time.Second}.
+//
+// Handlers should return once their context is done.
+func WithMethodTimeouts(timeouts map[string]time.Duration) Option {
+	return func(r *Router) {
+		r.methodTimeouts = timeouts
+	}
+}
+
+// WithLocaleNumbers parses numeric path, query and header parameters using the given
+// decimal and digit grouping separators, eg. WithLocaleNumbers(",", ".") accepts "1.234,56".
+//
+// By default numbers must use "." as the decimal separator and no digit grouping.
+func WithLocaleNumbers(decimal, grouping string) Option {
+	return func(r *Router) {
+		r.numberFormat = strings.NewReplacer(grouping, "", decimal, ".")
+		if grouping == "" {
+			r.numberFormat = strings.NewReplacer(decimal, ".")
+		}
+	}
+}
+
+// WithPathMatchingCaseInsensitive matches the static segments of route paths without
+// regard to case, eg. "/Users/42" matches "/users/:id".
+//
+// Path parameters are passed to handlers with their original case. If redirect is true,
+// requests are instead redirected to the path with the canonical case, so that search
+// engines and caches only see one URL for each resource. GET and HEAD requests are
+// redirected with a 301 and all other methods with a 308.
+func WithPathMatchingCaseInsensitive(redirect bool) Option {
+	return func(r *Router) {
+		r.caseInsensitive = true
+		r.caseRedirect = redirect
+	}
+}
+
 // New creates a new Router. See Router for details.
 //
 // DefaultProtocol will be used if protocol is nil.
 func New(options ...Option) *Router {
-	r := &Router{protocol: DefaultProtocol, router: pat.New()}
+	r := &Router{
+		protocol:     DefaultProtocol,
+		router:       pat.New(),
+		recover:      true,
+		encodeStatus: http.StatusInternalServerError,
+	}
 	for _, option := range options {
 		option(r)
 	}
 	return r
 }
 
+func (r *Router) logError(req *http.Request, err error) {
+	if r.errorLogger != nil {
+		r.errorLogger(req, err)
+	}
+}
+
+// mapError converts err to an *ErrorResponse if any error mapper recognises it.
+func (r *Router) mapError(err error) error {
+	var response *ErrorResponse
+	var typed *TypedError
+	if errors.As(err, &response) || errors.As(err, &typed) {
+		return err
+	}
+	if tooLarge := bodyTooLarge(err); tooLarge != nil {
+		return tooLarge
+	}
+	for _, mapper := range r.errorMappers {
+		if code, ok := mapper(err); ok {
+			return &ErrorResponse{Status: code, Message: err.Error()}
+		}
+	}
+	return err
+}
+
+// bodyTooLarge returns a 413 if err was caused by a request body exceeding the limit set
+// by WithMaxBodySize, or nil otherwise.
+func bodyTooLarge(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return Errorf(http.StatusRequestEntityTooLarge, "request body exceeds the maximum of %d bytes", tooLarge.Limit)
+	}
+	return nil
+}
+
+// wrapError annotates an error returned by the handler for rt, if enabled.
+func (r *Router) wrapError(rt route, err error) error {
+	if !r.wrapErrors {
+		return err
+	}
+	return fmt.Errorf("%s %s: %w", strings.ToLower(rt.method), rt.path, err)
+}
+
 func (r *Router) returnError(req *http.Request, w http.ResponseWriter, code int, err error) {
-	// TODO: Log this somehow.
-	r.protocol.EncodeServerResponse(req, w, code, err, nil) // nolint
+	r.returnErrorWithCause(req, w, code, err, err)
+}
+
+// returnErrorWithCause returns err to the client, logging cause in its place.
+func (r *Router) returnErrorWithCause(req *http.Request, w http.ResponseWriter, code int, err, cause error) {
+	mapped := r.mapError(err)
+	if r.errorDetail == ErrorDetailProduction {
+		if status := errorStatus(code, mapped); status >= 500 {
+			id := newCorrelationID()
+			r.logError(req, fmt.Errorf("correlation ID %s: %w", id, cause))
+			err = Errorf(status, "%s (correlation ID %s)", http.StatusText(status), id)
+			r.responseProtocol(req).EncodeServerResponse(req, w, code, err, nil) // nolint
+			return
+		}
+	}
+	r.logError(req, cause)
+	r.responseProtocol(req).EncodeServerResponse(req, w, code, mapped, nil) // nolint
+}
+
+// panicError returns the error to send to the client for a recovered panic value.
+//
+// Panics with an error that is, or maps to, an *ErrorResponse are returned as that
+// error. Anything else results in a generic 500.
+func (r *Router) panicError(v interface{}) error {
+	if err, ok := v.(error); ok {
+		var response *ErrorResponse
+		if mapped := r.mapError(err); errors.As(mapped, &response) {
+			return mapped
+		}
+	}
+	return Error(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+// errorStatus returns the HTTP status that err will be returned to the client with.
+func errorStatus(code int, err error) int {
+	var response *ErrorResponse
+	if errors.As(err, &response) {
+		return response.Status
+	}
+	var typed *TypedError
+	if errors.As(err, &typed) {
+		return typed.Status
+	}
+	if code != 0 {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+func newCorrelationID() string {
+	id := make([]byte, 8)
+	rand.Read(id) // nolint
+	return hex.EncodeToString(id)
 }
 
 // Add manually adds a route.
-func (r *Router) Add(method, path string, f interface{}) *Router {
-	handler := r.buildHandler(method, path, f)
-	r.router.Add(method, path, handler)
+func (r *Router) Add(method, path string, f interface{}, options ...RouteOption) *Router {
+	r.checkFrozen("add " + method + " " + path)
+	rt := route{method: method, path: path, handler: f}
+	for _, option := range options {
+		option(&rt)
+	}
+	for _, alias := range rt.aliases {
+		if strings.Join(pathParamNames(alias), "/") != strings.Join(pathParamNames(path), "/") {
+			panic("alias " + alias + " must have the same parameters as " + path)
+		}
+	}
+	rt.serve = r.buildHandler(rt)
+	r.routes = append(r.routes, rt)
+	r.router.Add(method, path, rt.serve)
+	for _, alias := range rt.aliases {
+		aliased := rt
+		aliased.path, aliased.aliases = alias, nil
+		r.routes = append(r.routes, aliased)
+		r.router.Add(method, alias, rt.serve)
+	}
+	return r
+}
+
+// Mount all routes from child under prefix.
+//
+// Mounted routes retain the configuration of the child, including its Protocol.
+func (r *Router) Mount(prefix string, child *Router) *Router {
+	r.checkFrozen("mount at " + prefix)
+	prefix = "/" + strings.Trim(prefix, "/")
+	if r.mounts[prefix] {
+		panic("a router is already mounted at " + prefix)
+	}
+	routes := make([]route, 0, len(child.routes))
+	for _, rt := range child.routes {
+		rt.path = joinPath(prefix, rt.path)
+		for _, existing := range r.routes {
+			if existing.method == rt.method && existing.path == rt.path {
+				panic("can't mount " + rt.method + " " + rt.path + " as it conflicts with an existing route")
+			}
+		}
+		routes = append(routes, rt)
+	}
+	for _, rt := range routes {
+		r.routes = append(r.routes, rt)
+		r.router.Add(rt.method, rt.path, rt.serve)
+	}
+	if r.mounts == nil {
+		r.mounts = map[string]bool{}
+	}
+	r.mounts[prefix] = true
 	return r
 }
 
-func (r *Router) Del(path string, f interface{}) *Router {
-	return r.Add("DEL", path, f)
+// Del is an alias for Delete.
+//
+// Deprecated: Use Delete.
+func (r *Router) Del(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Delete(path, f, options...)
+}
+
+func (r *Router) Delete(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("DELETE", path, f, options...)
 }
 
-func (r *Router) Get(path string, f interface{}) *Router {
-	return r.Add("GET", path, f)
+func (r *Router) Get(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("GET", path, f, options...)
 }
 
-func (r *Router) Head(path string, f interface{}) *Router {
-	return r.Add("HEAD", path, f)
+func (r *Router) Head(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("HEAD", path, f, options...)
 }
 
-func (r *Router) Options(path string, f interface{}) *Router {
-	return r.Add("OPTIONS", path, f)
+func (r *Router) Options(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("OPTIONS", path, f, options...)
 }
 
-func (r *Router) Patch(path string, f interface{}) *Router {
-	return r.Add("PATCH", path, f)
+func (r *Router) Patch(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("PATCH", path, f, options...)
 }
 
-func (r *Router) Post(path string, f interface{}) *Router {
-	return r.Add("POST", path, f)
+func (r *Router) Post(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("POST", path, f, options...)
 }
 
-func (r *Router) Put(path string, f interface{}) *Router {
-	return r.Add("PUT", path, f)
+func (r *Router) Put(path string, f interface{}, options ...RouteOption) *Router {
+	return r.Add("PUT", path, f, options...)
+}
+
+// Routes returns all registered routes, excluding those added WithHidden().
+func (r *Router) Routes() []RouteInfo {
+	out := []RouteInfo{}
+	for _, rt := range r.routes {
+		if rt.hidden {
+			continue
+		}
+		out = append(out, RouteInfo{Method: rt.method, Path: rt.path, Handler: rt.handler})
+	}
+	return out
+}
+
+// Describe returns a human-readable table of all routes, excluding those added WithHidden(),
+// with their methods, paths and handler signatures.
+func (r *Router) Describe() string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tHANDLER")
+	for _, route := range r.Routes() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", route.Method, route.Path, reflect.TypeOf(route.Handler))
+	}
+	w.Flush() // nolint
+	return buf.String()
+}
+
+// MountHandler serves GET and HEAD requests under prefix with handler, eg. to serve static
+// assets with http.FileServer.
+//
+// The prefix is stripped from the request path before it is passed to handler. If handler
+// responds with a 404 it is replaced with the Router's own 404 error response. The prefix
+// should not overlap with other routes, as they will be shadowed by handler.
+//
+// eg.
+//
+//	r.MountHandler("/static", http.FileServer(http.Dir("./static")))
+func (r *Router) MountHandler(prefix string, handler http.Handler) *Router {
+	r.checkFrozen("mount at " + prefix)
+	prefix = "/" + strings.Trim(prefix, "/")
+	if r.mounts[prefix] {
+		panic("a router is already mounted at " + prefix)
+	}
+	handler = http.StripPrefix(prefix, handler)
+	serve := func(w http.ResponseWriter, req *http.Request) {
+		header := w.Header().Clone()
+		nw := &notFoundWriter{ResponseWriter: w}
+		handler.ServeHTTP(nw, req)
+		if nw.notFound {
+			for key := range w.Header() {
+				delete(w.Header(), key)
+			}
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			r.returnError(req, w, 0, Error(http.StatusNotFound, http.StatusText(http.StatusNotFound)))
+		}
+	}
+	r.router.Get(strings.TrimSuffix(prefix, "/")+"/", http.HandlerFunc(serve))
+	if r.mounts == nil {
+		r.mounts = map[string]bool{}
+	}
+	r.mounts[prefix] = true
+	return r
+}
+
+// notFoundWriter discards 404 responses, recording that one occurred.
+type notFoundWriter struct {
+	http.ResponseWriter
+	notFound bool
+}
+
+func (n *notFoundWriter) WriteHeader(code int) {
+	if code == http.StatusNotFound {
+		n.notFound = true
+		return
+	}
+	n.ResponseWriter.WriteHeader(code)
+}
+
+func (n *notFoundWriter) Write(data []byte) (int, error) {
+	if n.notFound {
+		return len(data), nil
+	}
+	return n.ResponseWriter.Write(data)
+}
+
+// Freeze the Router, preventing any further routes from being added or mounted.
+//
+// Once frozen, the Router's routes may be safely read concurrently.
+func (r *Router) Freeze() *Router {
+	r.frozen = true
+	return r
+}
+
+func (r *Router) checkFrozen(what string) {
+	if r.frozen {
+		panic("can't " + what + " as router is frozen")
+	}
+}
+
+type protocolKey struct{}
+
+// extensionProtocol returns the Protocol registered for the extension of path, and path
+// without its extension.
+func (r *Router) extensionProtocol(urlPath string) (Protocol, string) {
+	ext := path.Ext(urlPath)
+	if protocol, ok := r.extensions[ext]; ok && ext != "" {
+		return protocol, strings.TrimSuffix(urlPath, ext)
+	}
+	return nil, urlPath
+}
+
+// responseProtocol returns the Protocol used to encode responses to req.
+func (r *Router) responseProtocol(req *http.Request) Protocol {
+	if protocol, ok := req.Context().Value(protocolKey{}).(Protocol); ok {
+		return protocol
+	}
+	return r.protocol
+}
+
+// joinPath joins a prefix and path, collapsing any duplicate slashes.
+func joinPath(prefix, path string) string {
+	joined := prefix + "/" + path
+	for strings.Contains(joined, "//") {
+		joined = strings.Replace(joined, "//", "/", -1)
+	}
+	return joined
+}
+
+// Use adds middleware wrapping every request served by the Router, including those that
+// don't match a route or that result in an error.
+//
+// Middleware is applied in the order it is added, with the first outermost. Middleware of
+// a Router mounted with Mount does not apply to its routes.
+func (r *Router) Use(middleware ...func(http.Handler) http.Handler) *Router {
+	r.checkFrozen("add middleware")
+	r.middleware = append(r.middleware, middleware...)
+	var handler http.Handler = http.HandlerFunc(r.serveHTTP)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	r.handler = handler
+	return r
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.handler != nil {
+		r.handler.ServeHTTP(w, req)
+		return
+	}
+	r.serveHTTP(w, req)
+}
+
+func (r *Router) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.maxBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(w, req.Body, r.maxBodySize)
+	}
+	if r.requestDump != nil {
+		if err := r.requestDump.dump(req); err != nil {
+			r.logError(req, err)
+		}
+	}
+	requestPath, requestURI := req.URL.Path, req.URL.RequestURI()
+	if protocol, trimmed := r.extensionProtocol(req.URL.Path); protocol != nil {
+		req = req.WithContext(context.WithValue(req.Context(), protocolKey{}, protocol))
+		u := *req.URL
+		u.Path, u.RawPath = trimmed, ""
+		req.URL = &u
+	}
+	if r.caseInsensitive {
+		if canonical := r.canonicalPath(req.URL.Path); canonical != req.URL.Path {
+			u := *req.URL
+			u.Path, u.RawPath = canonical, ""
+			if r.caseRedirect {
+				// Retain any extension stripped above.
+				u.Path += strings.TrimPrefix(requestPath, req.URL.Path)
+				// 301 allows clients to change the method to GET, so other methods are
+				// redirected with a 308 which preserves the method and body.
+				code := http.StatusPermanentRedirect
+				if req.Method == "GET" || req.Method == "HEAD" {
+					code = http.StatusMovedPermanently
+				}
+				http.Redirect(w, req, u.RequestURI(), code)
+				return
+			}
+			req.URL = &u
+		}
+	}
+	charset := r.negotiateCharset(req)
+	if r.singleflight != nil {
+		// Key on the request before the extension is stripped, as it selects the Protocol.
+		req = req.WithContext(context.WithValue(req.Context(), flightKey{}, requestURI+"\x00"+charset))
+	}
+	if charset != "" {
+		bw := newBufferedResponseWriter()
+		r.serve(bw, req)
+		if err := r.transcode(bw, charset); err != nil {
+			r.returnError(req, w, http.StatusNotAcceptable, err)
+			return
+		}
+		if err := bw.flush(w); err != nil {
+			r.logError(req, err)
+		}
+		return
+	}
+	r.serve(w, req)
+}
+
+func (r *Router) serve(w http.ResponseWriter, req *http.Request) {
+	if r.buffered {
+		bw := newBufferedResponseWriter()
+		r.router.ServeHTTP(bw, req)
+		if err := bw.flush(w); err != nil {
+			r.logError(req, err)
+		}
+		return
+	}
 	r.router.ServeHTTP(w, req)
 }
 
-func (r *Router) buildHandler(method string, path string, f interface{}) http.HandlerFunc {
-	r.routes = append(r.routes, route{method: method, path: path, handler: f})
-	fv := reflect.ValueOf(f)
+func (r *Router) buildHandler(rt route) http.HandlerFunc {
+	fv := reflect.ValueOf(rt.handler)
 	ft := fv.Type()
 	if ft.NumOut() == 0 {
 		panic("expected return signature of (..., error) but got " + ft.String())
@@ -130,39 +914,22 @@ func (r *Router) buildHandler(method string, path string, f interface{}) http.Ha
 	}
 	builders := []paramBuilder{}
 	paramIndex := 0
-	params := []string{}
-	for _, part := range strings.Split(path, "/") {
-		if strings.HasPrefix(part, ":") {
-			params = append(params, part[1:])
-		}
-	}
+	params := pathParamNames(rt.path)
 	haveBody := false
 	for i := 0; i < ft.NumIn(); i++ {
 		pt := ft.In(i)
-		var builder paramBuilder
-		if pt == contextType {
-			builder = func(r *http.Request) (reflect.Value, error) {
-				return reflect.ValueOf(r.Context()), nil
-			}
-		} else if pt == requestType {
-			builder = func(r *http.Request) (reflect.Value, error) {
-				return reflect.ValueOf(r), nil
-			}
-		} else {
+		builder := injectedParamBuilder(pt)
+		if builder == nil && isParamsType(pt) {
+			builder = r.paramsBuilder(rt, pt)
+		} else if builder == nil {
 			if paramIndex < len(params) {
-				builder = r.pathParamBuilder(pt, params[paramIndex], paramIndex)
+				builder = r.pathParamBuilder(pt, params[paramIndex], rt.paramValue(params[paramIndex]))
 				paramIndex++
 			} else {
 				if haveBody {
 					panic("have already mapped all path parameters and request body, but have arguments remaining in " + ft.String())
 				}
-				if pt.Kind() == reflect.Ptr {
-					pt = pt.Elem()
-				}
-				builder = func(req *http.Request) (reflect.Value, error) {
-					v := reflect.New(pt)
-					return v, r.protocol.DecodeClientRequest(req, v.Interface())
-				}
+				builder = r.bodyBuilder(rt, pt)
 				haveBody = true
 			}
 		}
@@ -171,60 +938,487 @@ func (r *Router) buildHandler(method string, path string, f interface{}) http.Ha
 		}
 		builders = append(builders, builder)
 	}
+	if _, body := routeInputs(rt); rt.bodySchema != nil && body == nil {
+		panic("route " + rt.method + " " + rt.path + " has a body schema but does not accept a body")
+	}
+	if rt.cache != nil && rt.method != "GET" && rt.method != "HEAD" {
+		panic("route " + rt.method + " " + rt.path + " can't be cached, only GET and HEAD routes may be")
+	}
+	if rt.cache != nil && ft.NumOut() > 1 && isUncacheableType(ft.Out(0)) {
+		panic("route " + rt.method + " " + rt.path + " returns " + ft.Out(0).String() + " which can't be cached")
+	}
+	if len(rt.scopes) > 0 && r.authorizer == nil {
+		panic("route " + rt.method + " " + rt.path + " has scopes but no authorizer is configured with WithAuthorizer")
+	}
 	return func(w http.ResponseWriter, req *http.Request) {
-		// Build parameters.
-		var err error
-		params := make([]reflect.Value, len(builders))
-		for i, builder := range builders {
-			params[i], err = builder(req)
-			if err != nil {
-				r.returnError(req, w, http.StatusUnprocessableEntity, err)
+		if r.recover {
+			defer func() {
+				if v := recover(); v != nil {
+					// By convention this aborts the response, so let net/http do that.
+					if v == http.ErrAbortHandler {
+						panic(v)
+					}
+					cause := fmt.Errorf("panic in %s %s: %v\n%s", rt.method, rt.path, v, debug.Stack())
+					r.returnErrorWithCause(req, w, 0, r.panicError(v), cause)
+				}
+			}()
+		}
+		if len(r.values) > 0 {
+			ctx := req.Context()
+			for _, value := range r.values {
+				ctx = context.WithValue(ctx, value.key, value.value)
+			}
+			req = req.WithContext(ctx)
+		}
+		if timeout, ok := r.methodTimeouts[req.Method]; ok {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			req = req.WithContext(ctx)
+		}
+		req, trailers := withTrailers(req)
+		defer trailers.write(w)
+		if err := r.checkAccept(req); err != nil {
+			r.returnError(req, w, 0, err)
+			return
+		}
+		if len(rt.scopes) > 0 {
+			if err := r.authorizer(req.Context(), rt.scopes); err != nil {
+				r.returnError(req, w, http.StatusForbidden, err)
 				return
 			}
 		}
-		ret := fv.Call(params)
-		switch len(ret) {
-		case 1: // (error)
-			err := ret[0].Interface()
-			if err != nil {
-				r.protocol.EncodeServerResponse(req, w, 0, err.(error), nil)
-			} else {
-				r.protocol.EncodeServerResponse(req, w, 0, nil, nil)
+		respond := func(w http.ResponseWriter) {
+			var ret []reflect.Value
+			var cacheKey string
+			if rt.cache != nil {
+				cacheKey = rt.cacheKey(func(name string) string { return rt.paramValue(name)(req) })
+				ret = rt.cache.get(cacheKey)
 			}
+			if ret == nil {
+				// Build parameters.
+				var err error
+				params := make([]reflect.Value, len(builders))
+				for i, builder := range builders {
+					params[i], err = builder(req)
+					if err != nil {
+						r.returnError(req, w, http.StatusUnprocessableEntity, err)
+						return
+					}
+				}
+				ret = fv.Call(params)
+				if rt.cache != nil && isCacheable(ret) {
+					rt.cache.put(cacheKey, ret)
+				}
+			}
+			switch len(ret) {
+			case 1: // (error)
+				err := ret[0].Interface()
+				if err != nil {
+					r.returnError(req, w, 0, r.wrapError(rt, err.(error)))
+				} else {
+					r.responseProtocol(req).EncodeServerResponse(req, w, 0, nil, nil)
+				}
 
-		case 2:
-			err := ret[1].Interface()
-			if err != nil {
-				r.protocol.EncodeServerResponse(req, w, 0, err.(error), nil)
-			} else if ret[0].Type() == reflect.TypeOf(StatusCode(0)) {
-				r.protocol.EncodeServerResponse(req, w, int(ret[0].Interface().(StatusCode)), nil, nil)
-			} else {
-				body := ret[0].Interface()
-				r.protocol.EncodeServerResponse(req, w, 0, nil, body)
+			case 2:
+				err := ret[1].Interface()
+				if err != nil {
+					r.returnError(req, w, 0, r.wrapError(rt, err.(error)))
+				} else if ret[0].Type() == reflect.TypeOf(StatusCode(0)) {
+					r.responseProtocol(req).EncodeServerResponse(req, w, int(ret[0].Interface().(StatusCode)), nil, nil)
+				} else {
+					body := ret[0].Interface()
+					r.writeRouteBody(rt, req, w, 0, body)
+				}
+			case 3:
+				err := ret[2].Interface()
+				if err != nil {
+					r.returnError(req, w, 0, r.wrapError(rt, err.(error)))
+				} else {
+					code := int(ret[1].Int())
+					body := ret[0].Interface()
+					r.writeRouteBody(rt, req, w, code, body)
+				}
+			}
+		}
+		if key, ok := req.Context().Value(flightKey{}).(string); ok && r.singleflight != nil && req.Method == "GET" && len(rt.scopes) == 0 {
+			bw := r.singleflight.do(key, func() *bufferedResponseWriter {
+				bw := newBufferedResponseWriter()
+				respond(bw)
+				return bw
+			})
+			if bw == nil {
+				r.returnError(req, w, http.StatusInternalServerError, errors.New("coalesced request failed"))
+				return
 			}
-		case 3:
-			err := ret[2].Interface()
-			if err != nil {
-				r.protocol.EncodeServerResponse(req, w, 0, err.(error), nil)
+			if err := bw.flush(w); err != nil {
+				r.logError(req, err)
+			}
+			return
+		}
+		respond(w)
+	}
+}
+
+func (r *Router) checkAccept(req *http.Request) error {
+	if r.accept == acceptAny {
+		return nil
+	}
+	accept := strings.TrimSpace(req.Header.Get("Accept"))
+	if accept == "" {
+		return Error(http.StatusNotAcceptable, "Accept header is required")
+	}
+	if r.accept == acceptRequired {
+		return nil
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		if mediaType := strings.TrimSpace(strings.Split(mediaRange, ";")[0]); mediaType != "*/*" {
+			return nil
+		}
+	}
+	return Error(http.StatusNotAcceptable, "Accept header must specify a media type")
+}
+
+// canonicalPath returns p with its static segments replaced by those of the first route
+// that matches them case-insensitively, or p itself if there is no such route.
+func (r *Router) canonicalPath(p string) string {
+	parts := strings.Split(p, "/")
+	var canonical string
+	for _, rt := range r.routes {
+		pattern := strings.Split(rt.path, "/")
+		if len(pattern) != len(parts) {
+			continue
+		}
+		matched := make([]string, len(parts))
+		for i, part := range pattern {
+			if strings.HasPrefix(part, ":") && parts[i] != "" {
+				matched[i] = parts[i]
+			} else if strings.EqualFold(part, parts[i]) {
+				matched[i] = part
 			} else {
-				code := int(ret[1].Int())
-				body := ret[0].Interface()
-				r.protocol.EncodeServerResponse(req, w, code, nil, body)
+				matched = nil
+				break
+			}
+		}
+		if matched == nil {
+			continue
+		}
+		if candidate := strings.Join(matched, "/"); candidate == p {
+			return p
+		} else if canonical == "" {
+			canonical = candidate
+		}
+	}
+	if canonical == "" {
+		return p
+	}
+	return canonical
+}
+
+// pathParamNames returns the names of the parameters in a route path.
+func pathParamNames(path string) []string {
+	params := []string{}
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, ":") {
+			params = append(params, part[1:])
+		}
+	}
+	return params
+}
+
+// injectedParamBuilder returns a paramBuilder for types that are injected from the request
+// itself, or nil if pt is not one of those types.
+func injectedParamBuilder(pt reflect.Type) paramBuilder {
+	switch pt {
+	case contextType:
+		return func(r *http.Request) (reflect.Value, error) {
+			return reflect.ValueOf(r.Context()), nil
+		}
+	case requestType:
+		return func(r *http.Request) (reflect.Value, error) {
+			return reflect.ValueOf(r), nil
+		}
+	case headerType:
+		return func(r *http.Request) (reflect.Value, error) {
+			return reflect.ValueOf(r.Header), nil
+		}
+	}
+	return nil
+}
+
+// bodyBuilder returns a paramBuilder that decodes the request body for rt into a value of type pt.
+func (r *Router) bodyBuilder(rt route, pt reflect.Type) paramBuilder {
+	builder := r.bodyDecoder(pt)
+	if rt.bodySchema != nil {
+		builder = r.schemaBuilder(rt.bodySchema, builder)
+	}
+	if len(rt.contentTypes) > 0 {
+		builder = contentTypeBuilder(rt.contentTypes, builder)
+	}
+	if rt.defaultBody != nil {
+		builder = defaultBodyBuilder(pt, rt.defaultBody, builder)
+	}
+	return builder
+}
+
+// defaultBodyBuilder wraps builder to use the value returned by supplier for empty bodies.
+func defaultBodyBuilder(pt reflect.Type, supplier func() interface{}, builder paramBuilder) paramBuilder {
+	convert := func(v interface{}) (reflect.Value, bool) {
+		value := reflect.ValueOf(v)
+		switch {
+		case !value.IsValid():
+			return reflect.Zero(pt), true
+		case value.Type() == pt:
+			return value, true
+		case pt.Kind() == reflect.Ptr && value.Type() == pt.Elem():
+			ptr := reflect.New(pt.Elem())
+			ptr.Elem().Set(value)
+			return ptr, true
+		case value.Kind() == reflect.Ptr && value.Type().Elem() == pt:
+			return value.Elem(), true
+		}
+		return value, false
+	}
+	if value, ok := convert(supplier()); !ok {
+		panic("default body of type " + value.Type().String() + " is not compatible with " + pt.String())
+	}
+	return func(req *http.Request) (reflect.Value, error) {
+		if req.Body == nil {
+			value, _ := convert(supplier())
+			return value, nil
+		}
+		head := make([]byte, 1)
+		n, err := io.ReadFull(req.Body, head)
+		if err == io.EOF {
+			value, _ := convert(supplier())
+			return value, nil
+		} else if err != nil {
+			return reflect.Value{}, err
+		}
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head[:n]), req.Body), req.Body}
+		return builder(req)
+	}
+}
+
+// contentTypeBuilder wraps builder to reject request bodies that are not one of mediaTypes.
+func contentTypeBuilder(mediaTypes []string, builder paramBuilder) paramBuilder {
+	return func(req *http.Request) (reflect.Value, error) {
+		contentType := req.Header.Get("Content-Type")
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		for _, allowed := range mediaTypes {
+			if strings.EqualFold(mediaType, allowed) {
+				return builder(req)
+			}
+		}
+		return reflect.Value{}, Errorf(http.StatusUnsupportedMediaType, "unsupported content type %q, must be one of %s", contentType, strings.Join(mediaTypes, ", "))
+	}
+}
+
+// bodyDecoder returns a paramBuilder that decodes the request body into a value of type pt.
+func (r *Router) bodyDecoder(pt reflect.Type) paramBuilder {
+	if pt == jsonArrayType {
+		return func(req *http.Request) (reflect.Value, error) {
+			array, err := newJSONArray(req)
+			return reflect.ValueOf(array), err
+		}
+	}
+	if union, ok := r.oneOfs[pt]; ok {
+		return r.oneOfBuilder(union)
+	}
+	isPtr := pt.Kind() == reflect.Ptr
+	if isPtr {
+		pt = pt.Elem()
+	}
+	decode := r.decodeBody
+	if merge := r.queryMerger(pt); merge != nil {
+		decode = merge
+	}
+	if custom, ok := r.bodyDecoders[pt]; ok {
+		decode = func(req *http.Request, v interface{}) error {
+			if err := custom(req, v); err != nil {
+				return err
+			}
+			return validate(v)
+		}
+	}
+	return func(req *http.Request) (reflect.Value, error) {
+		v := reflect.New(pt)
+		if err := decode(req, v.Interface()); err != nil {
+			return v, err
+		}
+		if !isPtr {
+			v = v.Elem()
+		}
+		return v, nil
+	}
+}
+
+// decodeBody decodes the request body into v, which must be a pointer, then validates it.
+func (r *Router) decodeBody(req *http.Request, v interface{}) error {
+	if err := r.protocol.DecodeClientRequest(req, v); err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+// validate v if it implements Validator.
+func validate(v interface{}) error {
+	if validator, ok := v.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// writeRouteBody applies any route-specific processing to a response body before writing it.
+func (r *Router) writeRouteBody(rt route, req *http.Request, w http.ResponseWriter, code int, body interface{}) {
+	if rt.recordRanges {
+		var err error
+		if code, body, err = recordRange(req, w, code, body); err != nil {
+			r.returnError(req, w, 0, err)
+			return
+		}
+	}
+	r.writeBody(req, w, code, body)
+}
+
+// writeBody writes a successful response, bypassing the protocol for raw bodies.
+func (r *Router) writeBody(req *http.Request, w http.ResponseWriter, code int, body interface{}) {
+	if handler, ok := body.(http.Handler); ok {
+		handler.ServeHTTP(w, req)
+		return
+	}
+	if thunk, ok := body.(func() (interface{}, error)); ok {
+		// Don't bother producing the body if the client has gone away or the request has
+		// timed out.
+		if err := req.Context().Err(); err != nil {
+			code := http.StatusServiceUnavailable
+			if errors.Is(err, context.DeadlineExceeded) {
+				code = http.StatusGatewayTimeout
 			}
+			r.returnError(req, w, code, err)
+			return
+		}
+		body, err := thunk()
+		if err != nil {
+			r.returnError(req, w, 0, err)
+			return
+		}
+		r.writeBody(req, w, code, body)
+		return
+	}
+	if response := asResponse(body); response != nil {
+		for key, values := range response.Headers {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		if response.Status != 0 {
+			code = int(response.Status)
+		}
+		r.writeBody(req, w, code, response.Body)
+		return
+	}
+	if page := asPaginated(body); page != nil {
+		links := NewLinkBuilder(req.URL, CursorParam)
+		body := page.paginate(links)
+		if link := links.String(); link != "" {
+			w.Header().Add("Link", link)
 		}
+		r.writeBody(req, w, code, body)
+		return
 	}
+	if redirect := asRedirect(body); redirect != nil {
+		code := int(redirect.Status)
+		if code == 0 {
+			code = http.StatusFound
+		}
+		http.Redirect(w, req, redirect.Location, code)
+		return
+	}
+	if raw := asRaw(body); raw != nil {
+		if err := writeRaw(w, code, raw); err != nil {
+			r.logError(req, fmt.Errorf("failed to write response: %w", err))
+		}
+		return
+	}
+	if r.sparseFieldsets {
+		var err error
+		if body, err = sparseFieldset(req, body); err != nil {
+			r.encodingFailed(req, w, err)
+			return
+		}
+	}
+	if r.maxEncodeDepth > 0 {
+		if err := checkDepth(reflect.ValueOf(body), 0, r.maxEncodeDepth); err != nil {
+			r.returnError(req, w, r.encodeStatus, fmt.Errorf("failed to encode response: %w", err))
+			return
+		}
+	}
+	if err := r.responseProtocol(req).EncodeServerResponse(req, w, code, nil, body); err != nil {
+		r.encodingFailed(req, w, err)
+	}
+}
+
+// encodingFailed handles an error from encoding a response body.
+//
+// If the response is buffered it is discarded and replaced with an error response,
+// otherwise the headers have already been sent and the error can only be logged.
+func (r *Router) encodingFailed(req *http.Request, w http.ResponseWriter, err error) {
+	bw, ok := w.(*bufferedResponseWriter)
+	if !ok {
+		r.logError(req, err)
+		return
+	}
+	bw.reset()
+	r.returnError(req, bw, r.encodeStatus, fmt.Errorf("failed to encode response: %w", err))
 }
 
-func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex int) paramBuilder {
+// isScalarParamType returns true if pathParamBuilder supports pt.
+func isScalarParamType(pt reflect.Type) bool {
+	if pt == bigIntType {
+		return true
+	}
+	switch pt.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// pathParamBuilder returns a paramBuilder that parses the string returned by value into pt.
+func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, value func(r *http.Request) string) paramBuilder {
+	if r.numberFormat != nil && pt.Kind() != reflect.String && pt.Kind() != reflect.Bool {
+		raw, format := value, r.numberFormat
+		value = func(r *http.Request) string { return format.Replace(raw(r)) }
+	}
+	if pt == bigIntType {
+		return func(r *http.Request) (reflect.Value, error) {
+			value := value(r)
+			n, ok := new(big.Int).SetString(value, 10)
+			if !ok {
+				return reflect.Value{}, Errorf(http.StatusBadRequest, "invalid integer %q for parameter %s", value, paramName)
+			}
+			return reflect.ValueOf(n), nil
+		}
+	}
 	switch pt.Kind() {
 	case reflect.String:
 		return func(r *http.Request) (reflect.Value, error) {
-			return reflect.ValueOf(r.URL.Query().Get(":" + paramName)), nil
+			return reflect.ValueOf(value(r)), nil
+		}
+	case reflect.Bool:
+		return func(r *http.Request) (reflect.Value, error) {
+			b, err := strconv.ParseBool(value(r))
+			return reflect.ValueOf(b), err
 		}
 	case reflect.Float32:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseFloat(r.URL.Query().Get(":"+paramName), 32)
+			n, err := strconv.ParseFloat(value(r), 32)
 			if err == nil {
 				v = reflect.New(float32Type).Elem()
 				v.SetFloat(n)
@@ -234,7 +1428,7 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Float64:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseFloat(r.URL.Query().Get(":"+paramName), 64)
+			n, err := strconv.ParseFloat(value(r), 64)
 			if err == nil {
 				v = reflect.New(float64Type).Elem()
 				v.SetFloat(n)
@@ -244,7 +1438,7 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Int:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseInt(r.URL.Query().Get(":"+paramName), 10, 64)
+			n, err := strconv.ParseInt(value(r), 10, 64)
 			if err == nil {
 				v = reflect.New(intType).Elem()
 				v.SetInt(n)
@@ -254,7 +1448,7 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Int8:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseInt(r.URL.Query().Get(":"+paramName), 10, 8)
+			n, err := strconv.ParseInt(value(r), 10, 8)
 			if err == nil {
 				v = reflect.New(int8Type).Elem()
 				v.SetInt(n)
@@ -264,7 +1458,7 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Int16:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseInt(r.URL.Query().Get(":"+paramName), 10, 16)
+			n, err := strconv.ParseInt(value(r), 10, 16)
 			if err == nil {
 				v = reflect.New(int16Type).Elem()
 				v.SetInt(n)
@@ -274,7 +1468,7 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Int32:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseInt(r.URL.Query().Get(":"+paramName), 10, 32)
+			n, err := strconv.ParseInt(value(r), 10, 32)
 			if err == nil {
 				v = reflect.New(int32Type).Elem()
 				v.SetInt(n)
@@ -284,7 +1478,7 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Int64:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseInt(r.URL.Query().Get(":"+paramName), 10, 64)
+			n, err := strconv.ParseInt(value(r), 10, 64)
 			if err == nil {
 				v = reflect.New(int64Type).Elem()
 				v.SetInt(n)
@@ -294,9 +1488,9 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Uint:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseUint(r.URL.Query().Get(":"+paramName), 10, 64)
+			n, err := strconv.ParseUint(value(r), 10, 64)
 			if err == nil {
-				v := reflect.New(uintType).Elem()
+				v = reflect.New(uintType).Elem()
 				v.SetUint(n)
 			}
 			return v, err
@@ -304,9 +1498,9 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Uint8:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseUint(r.URL.Query().Get(":"+paramName), 10, 8)
+			n, err := strconv.ParseUint(value(r), 10, 8)
 			if err == nil {
-				v := reflect.New(uint8Type).Elem()
+				v = reflect.New(uint8Type).Elem()
 				v.SetUint(n)
 			}
 			return v, err
@@ -314,9 +1508,9 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Uint16:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseUint(r.URL.Query().Get(":"+paramName), 10, 16)
+			n, err := strconv.ParseUint(value(r), 10, 16)
 			if err == nil {
-				v := reflect.New(uint16Type).Elem()
+				v = reflect.New(uint16Type).Elem()
 				v.SetUint(n)
 			}
 			return v, err
@@ -324,9 +1518,9 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Uint32:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseUint(r.URL.Query().Get(":"+paramName), 10, 32)
+			n, err := strconv.ParseUint(value(r), 10, 32)
 			if err == nil {
-				v := reflect.New(uint32Type).Elem()
+				v = reflect.New(uint32Type).Elem()
 				v.SetUint(n)
 			}
 			return v, err
@@ -334,9 +1528,9 @@ func (r *Router) pathParamBuilder(pt reflect.Type, paramName string, paramIndex
 	case reflect.Uint64:
 		return func(r *http.Request) (reflect.Value, error) {
 			var v reflect.Value
-			n, err := strconv.ParseUint(r.URL.Query().Get(":"+paramName), 10, 64)
+			n, err := strconv.ParseUint(value(r), 10, 64)
 			if err == nil {
-				v := reflect.New(uint64Type).Elem()
+				v = reflect.New(uint64Type).Elem()
 				v.SetUint(n)
 			}
 			return v, err
@@ -2,11 +2,25 @@ package rest
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -116,3 +130,1300 @@ func postAndDecode(t *testing.T, server *httptest.Server, path string, req inter
 	}
 	return rep
 }
+
+type validatedRequest struct {
+	Name string
+}
+
+func (v validatedRequest) Validate() error {
+	if v.Name == "" {
+		return Error(http.StatusBadRequest, "name is required")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	r := New()
+	r.Post("/pointer", func(req *validatedRequest) (string, error) {
+		return req.Name, nil
+	})
+	r.Post("/value", func(req validatedRequest) (string, error) {
+		return req.Name, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, path := range []string{"/pointer", "/value"} {
+		t.Run("Valid"+path, func(t *testing.T) {
+			actual := ""
+			resp := postAndDecode(t, server, path, &validatedRequest{Name: "bob"}, &actual)
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+			require.Equal(t, "bob", actual)
+		})
+
+		t.Run("Invalid"+path, func(t *testing.T) {
+			actual := &ErrorResponse{}
+			resp := postAndDecode(t, server, path, &validatedRequest{}, actual)
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			require.Equal(t, Error(http.StatusBadRequest, "name is required"), actual)
+		})
+	}
+}
+
+func TestHiddenRoute(t *testing.T) {
+	r := New()
+	r.Get("/public", func() (string, error) { return "public", nil })
+	r.Get("/internal", func() (string, error) { return "internal", nil }, WithHidden())
+
+	routes := r.Routes()
+	require.Len(t, routes, 1)
+	require.Equal(t, "GET", routes[0].Method)
+	require.Equal(t, "/public", routes[0].Path)
+
+	spec, err := r.OpenAPI()
+	require.NoError(t, err)
+	require.NotContains(t, string(spec), "/internal")
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/internal", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "internal", actual)
+}
+
+func TestRawResponse(t *testing.T) {
+	r := New()
+	r.Get("/report.csv", func() (*Raw, error) {
+		return &Raw{ContentType: "text/csv", Body: strings.NewReader("a,b\n1,2\n")}, nil
+	})
+	r.Get("/blob", func() (io.Reader, StatusCode, error) {
+		return bytes.NewReader([]byte{0, 1, 2}), http.StatusAccepted, nil
+	})
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	r.Get("/image", func() (Raw, error) {
+		return Raw{Body: bytes.NewReader(png)}, nil
+	})
+	r.Get("/raw_error", func() (io.Reader, error) {
+		return nil, Error(http.StatusNotFound, "missing")
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Raw", func(t *testing.T) {
+		resp, body := getRaw(t, server, "/report.csv")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+		require.Equal(t, "a,b\n1,2\n", body)
+	})
+
+	t.Run("ReaderWithStatusCode", func(t *testing.T) {
+		resp, body := getRaw(t, server, "/blob")
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		require.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+		require.Equal(t, "\x00\x01\x02", body)
+	})
+
+	t.Run("SniffedContentType", func(t *testing.T) {
+		resp, body := getRaw(t, server, "/image")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		require.Equal(t, string(png), body)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/raw_error", actual)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		require.Equal(t, Error(http.StatusNotFound, "missing"), actual)
+	})
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk on fire") }
+
+func TestRawResponseCopyError(t *testing.T) {
+	logged := make(chan error, 1)
+	r := New(WithErrorLogger(func(req *http.Request, err error) { logged <- err }))
+	r.Get("/report.csv", func() (*Raw, error) {
+		return &Raw{ContentType: "text/csv", Body: failingReader{}}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/report.csv")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualError(t, <-logged, "failed to write response: disk on fire")
+}
+
+func getRaw(t *testing.T, server *httptest.Server, path string) (*http.Response, string) {
+	t.Helper()
+	resp, err := server.Client().Get(server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestRecover(t *testing.T) {
+	var logged error
+	r := New(WithErrorLogger(func(req *http.Request, err error) { logged = err }))
+	r.Get("/panic", func() error {
+		var m map[string]int
+		m["boom"] = 1
+		return nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &ErrorResponse{}
+	resp := getAndDecode(t, server, "/panic", actual)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, Error(http.StatusInternalServerError, "Internal Server Error"), actual)
+	require.Error(t, logged)
+	require.Contains(t, logged.Error(), "panic in GET /panic: assignment to entry in nil map")
+	require.Contains(t, logged.Error(), "goroutine")
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	var logged error
+	r := New(WithErrorLogger(func(req *http.Request, err error) { logged = err }))
+	r.Get("/abort", func() (http.Handler, error) {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("partial")) // nolint
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/abort")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	_, err = ioutil.ReadAll(resp.Body)
+	require.Error(t, err)
+	require.NoError(t, logged)
+}
+
+func TestRecoverErrorPanic(t *testing.T) {
+	r := New(WithErrorMapper(func(err error) (int, bool) {
+		var notFound *notFoundError
+		return http.StatusNotFound, errors.As(err, &notFound)
+	}))
+	r.Get("/error_response", func() error {
+		panic(Error(http.StatusServiceUnavailable, "unavailable"))
+	})
+	r.Get("/mapped", func() error {
+		panic(&notFoundError{1})
+	})
+	r.Get("/plain", func() error {
+		panic(fmt.Errorf("secret details"))
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("ErrorResponse", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/error_response", actual)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, Error(http.StatusServiceUnavailable, "unavailable"), actual)
+	})
+
+	t.Run("MappedError", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/mapped", actual)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		require.Equal(t, Error(http.StatusNotFound, "1 not found"), actual)
+	})
+
+	t.Run("PlainError", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/plain", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, Error(http.StatusInternalServerError, "Internal Server Error"), actual)
+	})
+}
+
+type taggedProtocol struct {
+	Protocol
+	tag string
+}
+
+func (p taggedProtocol) EncodeServerResponse(req *http.Request, w http.ResponseWriter, code int, err error, v interface{}) error {
+	w.Header().Set("X-Protocol", p.tag)
+	return p.Protocol.EncodeServerResponse(req, w, code, err, v)
+}
+
+func TestMount(t *testing.T) {
+	admin := New(WithProtocol(taggedProtocol{DefaultProtocol, "admin"}))
+	admin.Get("/users/:id", func(id int) (int, error) { return id, nil })
+	admin.Get("/", func() (string, error) { return "index", nil })
+
+	r := New()
+	r.Get("/health", func() (string, error) { return "ok", nil })
+	r.Mount("/admin/", admin)
+
+	require.Equal(t, []string{"/health", "/admin/users/:id", "/admin/"}, routePaths(r))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("ChildRoute", func(t *testing.T) {
+		actual := 0
+		resp := getAndDecode(t, server, "/admin/users/42", &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "admin", resp.Header.Get("X-Protocol"))
+		require.Equal(t, 42, actual)
+	})
+
+	t.Run("ChildIndex", func(t *testing.T) {
+		actual := ""
+		getAndDecode(t, server, "/admin/", &actual)
+		require.Equal(t, "index", actual)
+	})
+
+	t.Run("ParentRoute", func(t *testing.T) {
+		actual := ""
+		resp := getAndDecode(t, server, "/health", &actual)
+		require.Equal(t, "", resp.Header.Get("X-Protocol"))
+		require.Equal(t, "ok", actual)
+	})
+
+	t.Run("DuplicatePrefix", func(t *testing.T) {
+		require.Panics(t, func() { r.Mount("/admin", New()) })
+	})
+
+	t.Run("ConflictingRoute", func(t *testing.T) {
+		child := New()
+		child.Get("/health", func() error { return nil })
+		require.Panics(t, func() { r.Mount("/", child) })
+	})
+}
+
+func routePaths(r *Router) []string {
+	paths := []string{}
+	for _, route := range r.Routes() {
+		paths = append(paths, route.Path)
+	}
+	return paths
+}
+
+type notFoundError struct {
+	id int
+}
+
+func (n *notFoundError) Error() string { return fmt.Sprintf("%d not found", n.id) }
+
+func TestErrorMapper(t *testing.T) {
+	r := New(WithErrorMapper(func(err error) (int, bool) {
+		var notFound *notFoundError
+		if errors.As(err, &notFound) {
+			return http.StatusNotFound, true
+		}
+		return 0, false
+	}))
+	r.Get("/mapped/:id", func(id int) error {
+		return fmt.Errorf("lookup failed: %w", &notFoundError{id})
+	})
+	r.Get("/unmapped", func() error {
+		return fmt.Errorf("database unavailable")
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Mapped", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/mapped/10", actual)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		require.Equal(t, Error(http.StatusNotFound, "lookup failed: 10 not found"), actual)
+	})
+
+	t.Run("Unmapped", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/unmapped", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, Error(http.StatusInternalServerError, "database unavailable"), actual)
+	})
+}
+
+func TestBigIntPathParam(t *testing.T) {
+	r := New()
+	r.Get("/accounts/:id", func(id *big.Int) (string, error) {
+		return new(big.Int).Add(id, big.NewInt(1)).String(), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Valid", func(t *testing.T) {
+		actual := ""
+		resp := getAndDecode(t, server, "/accounts/123456789012345678901234567890", &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "123456789012345678901234567891", actual)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/accounts/12x", actual)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, Error(http.StatusBadRequest, `invalid integer "12x" for parameter id`), actual)
+	})
+}
+
+func TestUintPathParams(t *testing.T) {
+	r := New()
+	r.Get("/uint/:id", func(id uint) (uint, error) { return id, nil })
+	r.Get("/uint8/:id", func(id uint8) (uint8, error) { return id, nil })
+	r.Get("/uint16/:id", func(id uint16) (uint16, error) { return id, nil })
+	r.Get("/uint32/:id", func(id uint32) (uint32, error) { return id, nil })
+	r.Get("/uint64/:id", func(id uint64) (uint64, error) { return id, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	for _, kind := range []string{"uint", "uint8", "uint16", "uint32", "uint64"} {
+		t.Run(kind, func(t *testing.T) {
+			var actual uint64
+			resp := getAndDecode(t, server, "/"+kind+"/42", &actual)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, uint64(42), actual)
+		})
+	}
+
+	t.Run("OutOfRange", func(t *testing.T) {
+		resp := getAndDecode(t, server, "/uint8/256", nil)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestErrorWrapper(t *testing.T) {
+	var logged error
+	r := New(
+		WithErrorWrapper(),
+		WithErrorLogger(func(req *http.Request, err error) { logged = err }),
+	)
+	r.Get("/users/:id", func(id int) (string, error) {
+		return "", Errorf(http.StatusNotFound, "user %d not found", id)
+	})
+	r.Get("/fail", func() error {
+		return fmt.Errorf("failed")
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("StatusPreserved", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/users/7", actual)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		require.EqualError(t, logged, "get /users/:id: 404: user 7 not found")
+		var response *ErrorResponse
+		require.True(t, errors.As(logged, &response))
+		require.Equal(t, Error(http.StatusNotFound, "user 7 not found"), actual)
+	})
+
+	t.Run("Wrapped", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/fail", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, Error(http.StatusInternalServerError, "get /fail: failed"), actual)
+	})
+}
+
+func TestHeaders(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(id string, headers http.Header) (*Response, error) {
+		return &Response{
+			Headers: http.Header{"Cache-Control": {"no-cache"}, "X-Request-Id": {headers.Get("X-Request-Id")}},
+			Body:    id,
+		}, nil
+	})
+	r.Put("/users/:id", func(id string, name string) (Response, error) {
+		return Response{
+			Headers: http.Header{"Location": {"/users/" + id}},
+			Status:  http.StatusCreated,
+		}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("RequestAndResponseHeaders", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/users/bob", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Request-Id", "1234")
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		actual := ""
+		err = json.NewDecoder(resp.Body).Decode(&actual)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "1234", resp.Header.Get("X-Request-Id"))
+		require.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.Equal(t, "bob", actual)
+	})
+
+	t.Run("MissingRequestHeader", func(t *testing.T) {
+		resp := getAndDecode(t, server, "/users/bob", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "", resp.Header.Get("X-Request-Id"))
+	})
+
+	t.Run("StatusAndNoBody", func(t *testing.T) {
+		req, err := http.NewRequest("PUT", server.URL+"/users/bob", strings.NewReader(`"Bob"`))
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, "/users/bob", resp.Header.Get("Location"))
+	})
+}
+
+func TestFreeze(t *testing.T) {
+	r := New()
+	r.Get("/before", func() (string, error) { return "before", nil })
+	r.Freeze()
+
+	require.PanicsWithValue(t, "can't add GET /after as router is frozen", func() {
+		r.Get("/after", func() error { return nil })
+	})
+	require.Panics(t, func() { r.Mount("/child", New()) })
+	require.Equal(t, []string{"/before"}, routePaths(r))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/before", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "before", actual)
+}
+
+func TestChunkedTransferDisabled(t *testing.T) {
+	r := New(WithChunkedTransferDisabled())
+	r.Get("/large", func() (io.Reader, error) {
+		return strings.NewReader(strings.Repeat("x", 100000)), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, body := getRaw(t, server, "/large")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(100000), resp.ContentLength)
+	require.Empty(t, resp.TransferEncoding)
+	require.Len(t, body, 100000)
+}
+
+func TestReturnHandler(t *testing.T) {
+	r := New()
+	r.Get("/delegate/:role", func(role string) (http.Handler, error) {
+		if role != "admin" {
+			return nil, Error(http.StatusForbidden, "forbidden")
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("delegated " + req.URL.Path)) // nolint
+		}), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Delegated", func(t *testing.T) {
+		resp, body := getRaw(t, server, "/delegate/admin")
+		require.Equal(t, http.StatusTeapot, resp.StatusCode)
+		require.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+		require.Equal(t, "delegated /delegate/admin", body)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/delegate/user", actual)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestLazyBody(t *testing.T) {
+	called := make(chan bool, 2)
+	r := New()
+	r.Get("/lazy", func() (func() (interface{}, error), error) {
+		return func() (interface{}, error) {
+			called <- true
+			return &struct{ Message string }{"expensive"}, nil
+		}, nil
+	})
+	r.Get("/lazy_error", func() (func() (interface{}, error), error) {
+		return func() (interface{}, error) {
+			called <- true
+			return nil, Error(http.StatusConflict, "conflict")
+		}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Body", func(t *testing.T) {
+		actual := map[string]string{}
+		resp := getAndDecode(t, server, "/lazy", &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, map[string]string{"Message": "expensive"}, actual)
+		require.True(t, <-called)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/lazy_error", actual)
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		require.Equal(t, Error(http.StatusConflict, "conflict"), actual)
+		require.True(t, <-called)
+	})
+}
+
+func TestErrorDetailMode(t *testing.T) {
+	handler := func() error { return fmt.Errorf("connection to db-1 refused") }
+	badRequest := func() error { return Error(http.StatusBadRequest, "invalid name") }
+
+	t.Run("Development", func(t *testing.T) {
+		r := New(WithErrorDetailMode(ErrorDetailDevelopment))
+		r.Get("/fail", handler)
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/fail", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, Error(http.StatusInternalServerError, "connection to db-1 refused"), actual)
+	})
+
+	t.Run("Production", func(t *testing.T) {
+		logged := make(chan error, 1)
+		r := New(
+			WithErrorDetailMode(ErrorDetailProduction),
+			WithErrorLogger(func(req *http.Request, err error) { logged <- err }),
+		)
+		r.Get("/fail", handler)
+		r.Get("/bad", badRequest)
+		r.Get("/panic", func() error { panic(Errorf(http.StatusInternalServerError, "secret at db-1")) })
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/fail", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.NotContains(t, actual.Message, "db-1")
+		require.Regexp(t, `^Internal Server Error \(correlation ID ([0-9a-f]{16})\)$`, actual.Message)
+		id := actual.Message[len(actual.Message)-17 : len(actual.Message)-1]
+		require.EqualError(t, <-logged, "correlation ID "+id+": connection to db-1 refused")
+
+		// Client errors are returned verbatim.
+		actual = &ErrorResponse{}
+		resp = getAndDecode(t, server, "/bad", actual)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, Error(http.StatusBadRequest, "invalid name"), actual)
+		<-logged
+
+		// Panics are masked like returned errors.
+		actual = &ErrorResponse{}
+		resp = getAndDecode(t, server, "/panic", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.NotContains(t, actual.Message, "db-1")
+		require.Regexp(t, `^Internal Server Error \(correlation ID ([0-9a-f]{16})\)$`, actual.Message)
+		id = actual.Message[len(actual.Message)-17 : len(actual.Message)-1]
+		require.Contains(t, (<-logged).Error(), "correlation ID "+id+": panic in GET /panic: 500: secret at db-1")
+	})
+}
+
+func TestRequireAccept(t *testing.T) {
+	get := func(t *testing.T, server *httptest.Server, accept string) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+"/", nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	handler := func() (string, error) { return "ok", nil }
+
+	t.Run("AllowWildcard", func(t *testing.T) {
+		r := New(WithRequireAccept(true))
+		r.Get("/", handler)
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		require.Equal(t, http.StatusNotAcceptable, get(t, server, "").StatusCode)
+		require.Equal(t, http.StatusOK, get(t, server, "application/json").StatusCode)
+		require.Equal(t, http.StatusOK, get(t, server, "*/*").StatusCode)
+	})
+
+	t.Run("DisallowWildcard", func(t *testing.T) {
+		r := New(WithRequireAccept(false))
+		r.Get("/", handler)
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		require.Equal(t, http.StatusNotAcceptable, get(t, server, "").StatusCode)
+		require.Equal(t, http.StatusNotAcceptable, get(t, server, "*/*;q=0.8").StatusCode)
+		require.Equal(t, http.StatusOK, get(t, server, "*/*, application/json").StatusCode)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	deleted := ""
+	r := New()
+	r.Delete("/todo/:id", func(id string) error {
+		deleted = id
+		return nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, err := http.NewRequest("DELETE", server.URL+"/todo/123", nil)
+	require.NoError(t, err)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "", resp.Header.Get("Content-Type"))
+	require.Empty(t, body)
+	require.Equal(t, "123", deleted)
+}
+
+func TestParamTransform(t *testing.T) {
+	r := New()
+	r.Get("/articles/:slug/:page", func(slug string, page int) (string, error) {
+		return fmt.Sprintf("%s:%d", slug, page), nil
+	},
+		WithParamTransform("slug", strings.ToLower),
+		WithParamTransform("page", func(s string) string { return strings.TrimPrefix(s, "p") }),
+	)
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/articles/Hello-World/p3", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "hello-world:3", actual)
+}
+
+type xmlProtocol struct {
+	Protocol
+}
+
+func (xmlProtocol) EncodeServerResponse(req *http.Request, w http.ResponseWriter, code int, err error, v interface{}) error {
+	if code == 0 {
+		code = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func TestExtensionProtocols(t *testing.T) {
+	type data struct {
+		Name string `json:"name" xml:"name"`
+	}
+	r := New(WithExtensionProtocols(map[string]Protocol{
+		".xml":  xmlProtocol{DefaultProtocol},
+		".json": DefaultProtocol,
+	}))
+	r.Get("/data", func() (*data, error) { return &data{Name: "teapot"}, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("XML", func(t *testing.T) {
+		resp, body := getRaw(t, server, "/data.xml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/xml", resp.Header.Get("Content-Type"))
+		require.Equal(t, "<data><name>teapot</name></data>", body)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		resp, body := getRaw(t, server, "/data.json")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.Equal(t, "{\"name\":\"teapot\"}\n", body)
+	})
+
+	t.Run("UnknownExtension", func(t *testing.T) {
+		resp, _ := getRaw(t, server, "/data.csv")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// checksumReader calls SetTrailer with a checksum of everything read once it reaches EOF.
+type checksumReader struct {
+	ctx    context.Context
+	reader io.Reader
+	hash   hash.Hash
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.hash.Write(p[:n]) // nolint
+	if err == io.EOF {
+		SetTrailer(c.ctx, "X-Checksum", hex.EncodeToString(c.hash.Sum(nil)))
+	}
+	return n, err
+}
+
+func TestTrailer(t *testing.T) {
+	r := New()
+	r.Get("/stream", func(ctx context.Context) (io.Reader, error) {
+		return &checksumReader{ctx: ctx, reader: strings.NewReader("hello world"), hash: sha256.New()}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, body := getRaw(t, server, "/stream")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "hello world", body)
+	sum := sha256.Sum256([]byte("hello world"))
+	require.Equal(t, hex.EncodeToString(sum[:]), resp.Trailer.Get("X-Checksum"))
+}
+
+type scopesKey struct{}
+
+func TestScopes(t *testing.T) {
+	r := New(WithAuthorizer(func(ctx context.Context, scopes []string) error {
+		granted, _ := ctx.Value(scopesKey{}).([]string)
+		for _, scope := range scopes {
+			found := false
+			for _, g := range granted {
+				found = found || g == scope
+			}
+			if !found {
+				return fmt.Errorf("missing scope %s", scope)
+			}
+		}
+		return nil
+	}))
+	r.Get("/users", func() ([]string, error) { return []string{"bob"}, nil }, WithScopes("read:users"))
+	r.Get("/public", func() (string, error) { return "public", nil })
+
+	// Simulate authentication middleware populating the granted scopes.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if token := req.Header.Get("Authorization"); token != "" {
+			req = req.WithContext(context.WithValue(req.Context(), scopesKey{}, strings.Split(token, " ")))
+		}
+		r.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	get := func(t *testing.T, path, token string, v interface{}) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", token)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		err = json.NewDecoder(resp.Body).Decode(v)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := get(t, "/users", "", actual)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+		require.Equal(t, Error(http.StatusForbidden, "missing scope read:users"), actual)
+	})
+
+	t.Run("Authorized", func(t *testing.T) {
+		actual := []string{}
+		resp := get(t, "/users", "write:users read:users", &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, []string{"bob"}, actual)
+	})
+
+	t.Run("NoScopes", func(t *testing.T) {
+		actual := ""
+		resp := get(t, "/public", "", &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("MissingAuthorizer", func(t *testing.T) {
+		require.Panics(t, func() { New().Get("/", func() error { return nil }, WithScopes("admin")) })
+	})
+}
+
+type databaseKey struct{}
+
+func TestWithValue(t *testing.T) {
+	db := map[int]string{1: "bob"}
+	r := New(WithValue(databaseKey{}, db))
+	r.Get("/users/:id", func(ctx context.Context, id int) (string, error) {
+		return ctx.Value(databaseKey{}).(map[int]string)[id], nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/users/1", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "bob", actual)
+}
+
+func TestContextKey(t *testing.T) {
+	database := NewContextKey[map[int]string]("database")
+	missing := NewContextKey[string]("missing")
+	r := New(database.Inject(map[int]string{1: "bob"}))
+	r.Get("/users/:id", func(ctx context.Context, id int) (string, error) {
+		if _, ok := missing.From(ctx); ok {
+			return "", errors.New("unexpected value")
+		}
+		db, ok := database.From(ctx)
+		if !ok {
+			return "", errors.New("missing database")
+		}
+		return db[id], nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/users/1", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "bob", actual)
+}
+
+func TestDescribe(t *testing.T) {
+	r := New()
+	r.Get("/users/:id", func(ctx context.Context, id int) (string, error) { return "", nil })
+	r.Post("/users", func(name string) error { return nil })
+	r.Get("/internal", func() error { return nil }, WithHidden())
+
+	require.Equal(t, ""+
+		"METHOD  PATH        HANDLER\n"+
+		"GET     /users/:id  func(context.Context, int) (string, error)\n"+
+		"POST    /users      func(string) error\n",
+		r.Describe())
+}
+
+func TestEncodingErrorStatus(t *testing.T) {
+	unserializable := func() (interface{}, error) { return map[string]interface{}{"ch": make(chan int)}, nil }
+
+	t.Run("Default", func(t *testing.T) {
+		r := New(WithChunkedTransferDisabled())
+		r.Get("/", unserializable)
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/", actual)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		require.Equal(t, Error(http.StatusInternalServerError, "failed to encode response: json: unsupported type: chan int"), actual)
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		r := New(WithChunkedTransferDisabled(), WithEncodingErrorStatus(http.StatusBadGateway))
+		r.Get("/", unserializable)
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		actual := &ErrorResponse{}
+		resp := getAndDecode(t, server, "/", actual)
+		require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		require.Equal(t, http.StatusBadGateway, actual.Status)
+	})
+}
+
+func TestContentTypes(t *testing.T) {
+	r := New()
+	r.Post("/avatars", func(avatar map[string]string) error { return nil }, WithContentTypes("application/json"))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := server.Client().Post(server.URL+"/avatars", "application/json; charset=utf-8", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, err = server.Client().Post(server.URL+"/avatars", "text/plain", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	actual := &ErrorResponse{}
+	err = json.NewDecoder(resp.Body).Decode(actual)
+	require.NoError(t, err)
+	require.Equal(t, `unsupported content type "text/plain", must be one of application/json`, actual.Message)
+}
+
+func TestPathMatchingCaseInsensitive(t *testing.T) {
+	r := New(WithPathMatchingCaseInsensitive(false))
+	r.Get("/users/:id/Profile", func(id string) (string, error) { return id, nil })
+	r.Get("/users", func() (string, error) { return "list", nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/USERS/Bob/profile", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "Bob", actual)
+
+	resp = getAndDecode(t, server, "/Users", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "list", actual)
+
+	resp, err := server.Client().Get(server.URL + "/Users/Bob/settings")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	r := New(WithMaxBodySize(16))
+	r.Post("/users", func(user map[string]string) (string, error) { return user["name"], nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := postAndDecode(t, server, "/users", map[string]string{"name": "Bob"}, &actual)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "Bob", actual)
+
+	errResp := &ErrorResponse{}
+	resp = postAndDecode(t, server, "/users", map[string]string{"name": strings.Repeat("Bob", 10)}, errResp)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestPathMatchingCaseInsensitiveRedirect(t *testing.T) {
+	r := New(WithPathMatchingCaseInsensitive(true))
+	r.Get("/users/:id", func(id string) (string, error) { return id, nil })
+	r.Post("/users", func(name string) (string, error) { return name, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(server.URL + "/Users/Bob?full=true")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	require.Equal(t, "/users/Bob?full=true", resp.Header.Get("Location"))
+
+	resp, err = client.Post(server.URL+"/Users", "application/json", strings.NewReader(`"Bob"`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusPermanentRedirect, resp.StatusCode)
+	require.Equal(t, "/users", resp.Header.Get("Location"))
+
+	actual := ""
+	resp = postAndDecode(t, server, "/USERS", "Bob", &actual)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "Bob", actual)
+
+	resp = getAndDecode(t, server, "/users/Bob", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "Bob", actual)
+}
+
+func TestEmptyObject(t *testing.T) {
+	r := New()
+	r.Put("/users/:id", func(id int) (EmptyObject, error) { return EmptyObject{}, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	req, err := http.NewRequest("PUT", server.URL+"/users/1", nil)
+	require.NoError(t, err)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, "{}\n", string(body))
+}
+
+func TestPrimitiveBody(t *testing.T) {
+	r := New()
+	r.Post("/greet", func(name string) (string, error) { return "Hello " + name, nil })
+	r.Post("/double", func(count int) (int, error) { return count * 2, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	greeting := ""
+	resp := postAndDecode(t, server, "/greet", "Bob", &greeting)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "Hello Bob", greeting)
+
+	count := 0
+	resp = postAndDecode(t, server, "/double", 21, &count)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, 42, count)
+
+	errResp := &ErrorResponse{}
+	resp = postAndDecode(t, server, "/double", "many", errResp)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestMountHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("alert(1)"), 0600)
+	require.NoError(t, err)
+
+	r := New()
+	r.Get("/users/:id", func(id string) (string, error) { return id, nil })
+	r.MountHandler("/static", http.FileServer(http.Dir(dir)))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, body := getRaw(t, server, "/static/app.js")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "alert(1)", body)
+
+	actual := &ErrorResponse{}
+	resp = getAndDecode(t, server, "/static/missing.js", actual)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, Error(http.StatusNotFound, "Not Found"), actual)
+
+	user := ""
+	resp = getAndDecode(t, server, "/users/bob", &user)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "bob", user)
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestBodyDecoder(t *testing.T) {
+	r := New(WithBodyDecoder(reflect.TypeOf(point{}), func(req *http.Request, v interface{}) error {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		p := v.(*point)
+		_, err = fmt.Sscanf(string(data), "%d,%d", &p.X, &p.Y)
+		return err
+	}))
+	r.Post("/points", func(p *point) (*point, error) { return p, nil })
+	r.Post("/sum", func(p point) (int, error) { return p.X + p.Y, nil })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := server.Client().Post(server.URL+"/points", "text/plain", strings.NewReader("3,4"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	actual := &point{}
+	err = json.NewDecoder(resp.Body).Decode(actual)
+	require.NoError(t, err)
+	require.Equal(t, &point{X: 3, Y: 4}, actual)
+
+	resp, err = server.Client().Post(server.URL+"/sum", "text/plain", strings.NewReader("3,4"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	sum := 0
+	err = json.NewDecoder(resp.Body).Decode(&sum)
+	require.NoError(t, err)
+	require.Equal(t, 7, sum)
+}
+
+func TestAliases(t *testing.T) {
+	calls := 0
+	r := New()
+	r.Get("/users/:id", func(id string) (string, error) {
+		calls++
+		return id, nil
+	}, WithAliases("/members/:id"))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	resp := getAndDecode(t, server, "/users/bob", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "bob", actual)
+	resp = getAndDecode(t, server, "/members/alice", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "alice", actual)
+	require.Equal(t, 2, calls)
+	require.Equal(t, []string{"/users/:id", "/members/:id"}, routePaths(r))
+
+	require.Panics(t, func() {
+		r.Get("/groups/:id", func(id string) error { return nil }, WithAliases("/teams/:name"))
+	})
+}
+
+func TestSeeOther(t *testing.T) {
+	r := New()
+	r.Post("/orders", func(order map[string]string) (*Redirect, error) {
+		return SeeOther("/orders/" + order["id"]), nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Post(server.URL+"/orders", "application/json", strings.NewReader(`{"id": "42"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	require.Equal(t, "/orders/42", resp.Header.Get("Location"))
+}
+
+func TestMethodTimeouts(t *testing.T) {
+	r := New(WithMethodTimeouts(map[string]time.Duration{
+		"GET":  10 * time.Millisecond,
+		"POST": 10 * time.Second,
+	}))
+	wait := func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "timeout", nil
+		case <-time.After(200 * time.Millisecond):
+			return "done", nil
+		}
+	}
+	r.Get("/wait", func(ctx context.Context) (string, error) { return wait(ctx) })
+	r.Post("/wait", func(ctx context.Context, body map[string]string) (string, error) { return wait(ctx) })
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := ""
+	getAndDecode(t, server, "/wait", &actual)
+	require.Equal(t, "timeout", actual)
+	postAndDecode(t, server, "/wait", map[string]string{}, &actual)
+	require.Equal(t, "done", actual)
+}
+
+func TestMethodTimeoutsLazyBody(t *testing.T) {
+	called := false
+	r := New(WithMethodTimeouts(map[string]time.Duration{"GET": 10 * time.Millisecond}))
+	r.Get("/lazy", func(ctx context.Context) (func() (interface{}, error), error) {
+		<-ctx.Done()
+		return func() (interface{}, error) {
+			called = true
+			return "expensive", nil
+		}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := &ErrorResponse{}
+	resp := getAndDecode(t, server, "/lazy", actual)
+	require.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	require.Equal(t, http.StatusGatewayTimeout, actual.Status)
+	require.False(t, called)
+}
+
+func TestDefaultBody(t *testing.T) {
+	type config struct {
+		Retries int
+	}
+	r := New()
+	r.Put("/config", func(cfg *config) (*config, error) { return cfg, nil },
+		WithDefaultBody(func() interface{} { return config{Retries: 3} }))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	put := func(body string) *config {
+		req, err := http.NewRequest("PUT", server.URL+"/config", strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		actual := &config{}
+		err = json.NewDecoder(resp.Body).Decode(actual)
+		require.NoError(t, err)
+		return actual
+	}
+	require.Equal(t, &config{Retries: 3}, put(""))
+	require.Equal(t, &config{Retries: 5}, put(`{"Retries": 5}`))
+
+	require.Panics(t, func() {
+		r.Post("/config", func(cfg *config) error { return nil },
+			WithDefaultBody(func() interface{} { return "nope" }))
+	})
+}
+
+func TestUse(t *testing.T) {
+	var order []string
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+	cors := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			next.ServeHTTP(w, req)
+		})
+	}
+	r := New()
+	r.Use(cors, trace("outer"), trace("inner"))
+	r.Get("/users/:id", func(id int) (int, error) {
+		order = append(order, "handler")
+		return id, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	actual := 0
+	resp := getAndDecode(t, server, "/users/1", &actual)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"outer", "inner", "handler"}, order)
+
+	resp, err := server.Client().Get(server.URL + "/missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	errResp := &ErrorResponse{}
+	resp = getAndDecode(t, server, "/users/bob", errResp)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	require.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+}
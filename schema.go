@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+)
+
+// A SchemaValidator validates a raw request body against a JSON Schema.
+//
+// This allows any JSON Schema implementation to be used without this package depending on it.
+type SchemaValidator func(schema, body []byte) error
+
+// WithSchemaValidator sets the SchemaValidator used to enforce schemas added with WithBodySchema.
+func WithSchemaValidator(validator SchemaValidator) Option {
+	return func(r *Router) {
+		r.validator = validator
+	}
+}
+
+// WithBodySchema validates the raw request body of a route against a JSON Schema before it
+// is decoded, rejecting bodies that do not conform with a 400.
+//
+// A SchemaValidator must also be configured with WithSchemaValidator.
+func WithBodySchema(schema []byte) RouteOption {
+	return func(r *route) {
+		r.bodySchema = schema
+	}
+}
+
+// schemaBuilder wraps builder to first validate the request body against schema.
+func (r *Router) schemaBuilder(schema []byte, builder paramBuilder) paramBuilder {
+	if r.validator == nil {
+		panic("WithBodySchema requires a SchemaValidator to be configured with WithSchemaValidator")
+	}
+	return func(req *http.Request) (reflect.Value, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := r.validator(schema, body); err != nil {
+			return reflect.Value{}, Errorf(http.StatusBadRequest, "request body does not match schema: %s", err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return builder(req)
+	}
+}
@@ -0,0 +1,68 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requiredFieldsValidator is a minimal SchemaValidator supporting only "required".
+func requiredFieldsValidator(schema, body []byte) error {
+	s := struct {
+		Required []string `json:"required"`
+	}{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return err
+	}
+	for _, name := range s.Required {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("missing required property %q", name)
+		}
+	}
+	return nil
+}
+
+func TestBodySchema(t *testing.T) {
+	type user struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	r := New(WithSchemaValidator(requiredFieldsValidator))
+	r.Post("/users", func(u *user) (*user, error) { return u, nil },
+		WithBodySchema([]byte(`{"type": "object", "required": ["name", "email"]}`)))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Valid", func(t *testing.T) {
+		actual := &user{}
+		resp := postAndDecode(t, server, "/users", &user{Name: "Bob", Email: "bob@example.com"}, actual)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, &user{Name: "Bob", Email: "bob@example.com"}, actual)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := postAndDecode(t, server, "/users", map[string]string{"name": "Bob"}, actual)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Equal(t, Error(http.StatusBadRequest, `request body does not match schema: missing required property "email"`), actual)
+	})
+
+	t.Run("MissingValidator", func(t *testing.T) {
+		r := New()
+		require.Panics(t, func() { r.Post("/users", func(u *user) error { return nil }, WithBodySchema([]byte(`{}`))) })
+	})
+
+	t.Run("NoBody", func(t *testing.T) {
+		r := New(WithSchemaValidator(requiredFieldsValidator))
+		require.Panics(t, func() { r.Get("/users", func() error { return nil }, WithBodySchema([]byte(`{}`))) })
+	})
+}
@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"sync"
+)
+
+// flightKey is the context key for the key identifying identical requests.
+type flightKey struct{}
+
+// flight is a single in-progress request that others may wait on.
+type flight struct {
+	done     chan struct{}
+	response *bufferedResponseWriter
+}
+
+// singleflight coalesces concurrent calls with the same key.
+type singleflight struct {
+	lock    sync.Mutex
+	flights map[string]*flight
+}
+
+// do calls fn and returns its result, unless a call for key is already in progress in
+// which case the result of that call is returned instead.
+func (s *singleflight) do(key string, fn func() *bufferedResponseWriter) *bufferedResponseWriter {
+	s.lock.Lock()
+	if f, ok := s.flights[key]; ok {
+		s.lock.Unlock()
+		<-f.done
+		return f.response
+	}
+	f := &flight{done: make(chan struct{})}
+	if s.flights == nil {
+		s.flights = map[string]*flight{}
+	}
+	s.flights[key] = f
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.flights, key)
+		s.lock.Unlock()
+		close(f.done)
+	}()
+	f.response = fn()
+	return f.response
+}
@@ -0,0 +1,49 @@
+//go:build go1.25
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleflight(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const requests = 5
+		var calls int32
+		release := make(chan struct{})
+		r := New(WithSingleflight())
+		r.Get("/report", func() (int32, error) {
+			<-release
+			return atomic.AddInt32(&calls, 1), nil
+		})
+
+		recorders := make([]*httptest.ResponseRecorder, requests)
+		wg := sync.WaitGroup{}
+		for i := range recorders {
+			recorders[i] = httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/report?year=2020", nil)
+			wg.Add(1)
+			go func(w *httptest.ResponseRecorder) {
+				defer wg.Done()
+				r.ServeHTTP(w, req)
+			}(recorders[i])
+		}
+		// Wait until one request is blocked in the handler and the rest are waiting on it.
+		synctest.Wait()
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		for _, w := range recorders {
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, "1\n", w.Body.String())
+		}
+	})
+}
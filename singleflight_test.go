@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleflightKeyedByExtension(t *testing.T) {
+	type data struct {
+		Name string `json:"name" xml:"name"`
+	}
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	r := New(WithSingleflight(), WithExtensionProtocols(map[string]Protocol{".xml": xmlProtocol{DefaultProtocol}}))
+	r.Get("/data", func() (*data, error) {
+		entered <- struct{}{}
+		<-release
+		return &data{Name: "teapot"}, nil
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	paths := []string{"/data.xml", "/data"}
+	responses := make([]*http.Response, len(paths))
+	errs := make([]error, len(paths))
+	wg := sync.WaitGroup{}
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			responses[i], errs[i] = server.Client().Get(server.URL + path)
+		}(i, path)
+	}
+	// Both requests must reach the handler, rather than one waiting on the other.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			close(release)
+			t.Fatal("requests with different extensions were coalesced")
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	for i, contentType := range []string{"application/xml", "application/json"} {
+		require.NoError(t, errs[i])
+		responses[i].Body.Close()
+		require.Equal(t, contentType, responses[i].Header.Get("Content-Type"))
+	}
+}
+
+func TestSingleflightSkipsScopedRoutes(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	r := New(WithSingleflight(), WithAuthorizer(func(ctx context.Context, scopes []string) error { return nil }))
+	r.Get("/admin", func() (string, error) {
+		entered <- struct{}{}
+		<-release
+		return "ok", nil
+	}, WithScopes("admin"))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	responses := make([]*http.Response, 2)
+	errs := make([]error, 2)
+	wg := sync.WaitGroup{}
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = server.Client().Get(server.URL + "/admin")
+		}(i)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			close(release)
+			t.Fatal("requests to a scoped route were coalesced")
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	for i := range responses {
+		require.NoError(t, errs[i])
+		responses[i].Body.Close()
+		require.Equal(t, http.StatusOK, responses[i].StatusCode)
+	}
+}
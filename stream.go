@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+)
+
+var jsonArrayType = reflect.TypeOf(&JSONArray{})
+
+// JSONArray streams the elements of a JSON array request body.
+//
+// Declaring the body parameter of a handler as a *JSONArray allows arbitrarily large
+// arrays to be processed one element at a time rather than being decoded in full.
+// The body is always decoded as JSON, regardless of the Router's Protocol.
+//
+//	r.Post("/events", func(events *rest.JSONArray) error {
+//		event := &Event{}
+//		for events.Next(event) {
+//			...
+//		}
+//		return events.Err()
+//	})
+type JSONArray struct {
+	dec  *json.Decoder
+	err  error
+	done bool
+}
+
+func newJSONArray(req *http.Request) (*JSONArray, error) {
+	dec := json.NewDecoder(req.Body)
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return nil, Errorf(http.StatusUnprocessableEntity, "expected a JSON array but got %v", token)
+	}
+	return &JSONArray{dec: dec}, nil
+}
+
+// Next decodes the next element of the array into v.
+//
+// It returns false once all elements have been consumed or an error occurs, after
+// which Err should be checked.
+func (a *JSONArray) Next(v interface{}) bool {
+	if a.done || a.err != nil {
+		return false
+	}
+	if !a.dec.More() {
+		a.done = true
+		_, a.err = a.dec.Token()
+		return false
+	}
+	if err := a.dec.Decode(v); err != nil {
+		if a.err = bodyTooLarge(err); a.err == nil {
+			a.err = Error(http.StatusUnprocessableEntity, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// Err returns the first error encountered while decoding the array, if any.
+//
+// Malformed elements are reported as an *ErrorResponse with a status of 422, or 413 if
+// the body exceeds the limit set by WithMaxBodySize.
+func (a *JSONArray) Err() error {
+	return a.err
+}
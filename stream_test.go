@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONArray(t *testing.T) {
+	type item struct {
+		N int
+	}
+	r := New()
+	r.Post("/sum", func(items *JSONArray) (int, StatusCode, error) {
+		sum := 0
+		for it := (item{}); items.Next(&it); {
+			sum += it.N
+		}
+		return sum, http.StatusOK, items.Err()
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Array", func(t *testing.T) {
+		items := []item{}
+		expected := 0
+		for i := 0; i < 10000; i++ {
+			items = append(items, item{N: i})
+			expected += i
+		}
+		actual := 0
+		resp := postAndDecode(t, server, "/sum", items, &actual)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("NotArray", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := postAndDecode(t, server, "/sum", item{N: 1}, actual)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		require.Equal(t, Error(http.StatusUnprocessableEntity, "expected a JSON array but got {"), actual)
+	})
+
+	t.Run("InvalidElement", func(t *testing.T) {
+		actual := &ErrorResponse{}
+		resp := postAndDecode(t, server, "/sum", []interface{}{item{N: 1}, "nope"}, actual)
+		require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestJSONArrayStreaming(t *testing.T) {
+	type item struct {
+		N int
+	}
+	first := make(chan int, 1)
+	r := New()
+	r.Post("/count", func(items *JSONArray) (int, StatusCode, error) {
+		count := 0
+		for it := (item{}); items.Next(&it); count++ {
+			if count == 0 {
+				first <- it.N
+			}
+		}
+		return count, http.StatusOK, items.Err()
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	body, w := io.Pipe()
+	done := make(chan result, 1)
+	go func() {
+		resp, err := server.Client().Post(server.URL+"/count", "application/json", body)
+		done <- result{resp, err}
+	}()
+
+	// The handler must receive the first element while the rest of the body is unwritten.
+	_, err := io.WriteString(w, `[{"N": 42},`)
+	require.NoError(t, err)
+	select {
+	case n := <-first:
+		require.Equal(t, 42, n)
+	case <-time.After(5 * time.Second):
+		w.CloseWithError(errors.New("timed out"))
+		t.Fatal("first element was not decoded before the body was complete")
+	}
+	_, err = io.WriteString(w, `{"N": 1}]`)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	res := <-done
+	require.NoError(t, res.err)
+	defer res.resp.Body.Close()
+	require.Equal(t, http.StatusOK, res.resp.StatusCode)
+	actual := 0
+	err = json.NewDecoder(res.resp.Body).Decode(&actual)
+	require.NoError(t, err)
+	require.Equal(t, 2, actual)
+}
+
+func TestJSONArrayMaxBodySize(t *testing.T) {
+	type item struct {
+		N int
+	}
+	r := New(WithMaxBodySize(1024))
+	r.Post("/sum", func(items *JSONArray) (int, StatusCode, error) {
+		sum := 0
+		for it := (item{}); items.Next(&it); {
+			sum += it.N
+		}
+		return sum, http.StatusOK, items.Err()
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	items := []item{}
+	for i := 0; i < 1000; i++ {
+		items = append(items, item{N: i})
+	}
+	actual := &ErrorResponse{}
+	resp := postAndDecode(t, server, "/sum", items, actual)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	require.Equal(t, Error(http.StatusRequestEntityTooLarge, "request body exceeds the maximum of 1024 bytes"), actual)
+}
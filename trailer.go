@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type trailersKey struct{}
+
+type trailers struct {
+	lock   sync.Mutex
+	header http.Header
+}
+
+// SetTrailer sets an HTTP trailer to be sent after the response body.
+//
+// ctx must be the context of a request being handled by a Router. As trailers are sent
+// once the body has been written, this may be called while a streaming body is being
+// produced, eg. to send a checksum of the body. Trailers are not sent for responses
+// buffered by WithChunkedTransferDisabled.
+func SetTrailer(ctx context.Context, key, value string) {
+	if t, ok := ctx.Value(trailersKey{}).(*trailers); ok {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.header.Set(key, value)
+	}
+}
+
+func withTrailers(req *http.Request) (*http.Request, *trailers) {
+	t := &trailers{header: http.Header{}}
+	return req.WithContext(context.WithValue(req.Context(), trailersKey{}, t)), t
+}
+
+// write any trailers set during the request to w.
+func (t *trailers) write(w http.ResponseWriter) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, values := range t.header {
+		w.Header()[http.TrailerPrefix+key] = values
+	}
+}
@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// WebhookVerify returns middleware that verifies the HMAC signature of request bodies,
+// as sent by webhook providers.
+//
+// The signature is read as hex from the header named header, optionally prefixed with
+// the algorithm as in "sha256=<hex>", and compared in constant time with the HMAC of
+// the body computed with algo and secret. Requests without a valid signature are
+// rejected with a 401. The body remains available to the wrapped handler.
+//
+// eg.
+//
+//	http.ListenAndServe(":8080", rest.WebhookVerify(secret, "X-Hub-Signature-256", sha256.New)(router))
+func WebhookVerify(secret []byte, header string, algo func() hash.Hash) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				DefaultProtocol.EncodeServerResponse(req, w, 0, Errorf(http.StatusBadRequest, "failed to read body: %s", err), nil) // nolint
+				return
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			signature := req.Header.Get(header)
+			if i := strings.Index(signature, "="); i >= 0 {
+				signature = signature[i+1:]
+			}
+			actual, err := hex.DecodeString(signature)
+			mac := hmac.New(algo, secret)
+			mac.Write(body) // nolint
+			if err != nil || !hmac.Equal(actual, mac.Sum(nil)) {
+				DefaultProtocol.EncodeServerResponse(req, w, 0, Error(http.StatusUnauthorized, "invalid webhook signature"), nil) // nolint
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
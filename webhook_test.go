@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookVerify(t *testing.T) {
+	secret := []byte("secret")
+	r := New()
+	r.Post("/hooks", func(event map[string]string) (string, error) { return event["action"], nil })
+
+	server := httptest.NewServer(WebhookVerify(secret, "X-Signature", sha256.New)(r))
+	defer server.Close()
+
+	post := func(body, signature string) *http.Response {
+		req, err := http.NewRequest("POST", server.URL+"/hooks", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Signature", signature)
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(`{"action":"opened"}`))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	resp := post(`{"action":"opened"}`, signature)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	actual := ""
+	err := json.NewDecoder(resp.Body).Decode(&actual)
+	require.NoError(t, err)
+	require.Equal(t, "opened", actual)
+
+	resp = post(`{"action":"closed"}`, signature)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	errResp := &ErrorResponse{}
+	err = json.NewDecoder(resp.Body).Decode(errResp)
+	require.NoError(t, err)
+	require.Equal(t, Error(http.StatusUnauthorized, "invalid webhook signature"), errResp)
+}